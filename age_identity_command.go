@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// runAgeIdentityCommand runs argv without a shell and returns the identities
+// it printed. stdout is never included in errors since it holds secrets, and
+// identities echoed to stderr are redacted. A command still running after
+// timeout, such as one waiting for an interactive unlock, is killed.
+func runAgeIdentityCommand(ctx context.Context, argv []string, timeout time.Duration) (string, error) {
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, argv[0], argv[1:]...)
+	cmd.WaitDelay = sopsWaitDelay
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("age identity command %q timed out after %s; raise operation_timeout if it needs longer", argv[0], timeout)
+	}
+	if err != nil {
+		stderrText := redactSecrets(strings.TrimSpace(stderr.String()))
+		if stderrText != "" {
+			stderrText = ": " + stderrText
+		}
+		return "", fmt.Errorf("age identity command %q failed: %w%s", argv[0], err, stderrText)
+	}
+
+	identities := stdout.String()
+	if _, err := parseAgeIdentities(identities); err != nil {
+		return "", fmt.Errorf("age identity command %q printed no usable identity: %w", argv[0], err)
+	}
+
+	return identities, nil
+}
+
+// ageIdentityCommandOutput runs the configured age_identity_command on first
+// use and caches its identities for the lifetime of the provider. Failures
+// are not cached so a later operation can retry.
+func (c *SopsProviderConfig) ageIdentityCommandOutput(ctx context.Context) (string, error) {
+	if len(c.AgeIdentityCommand) == 0 {
+		return "", nil
+	}
+
+	c.ageIdentityCommandMu.Lock()
+	defer c.ageIdentityCommandMu.Unlock()
+
+	if c.ageIdentityCommandIdentities != "" {
+		return c.ageIdentityCommandIdentities, nil
+	}
+
+	// The command counts as an operation and shares operation_timeout.
+	timeout := defaultSopsOperationTimeout
+	if c.Sops != nil && c.Sops.Timeout > 0 {
+		timeout = c.Sops.Timeout
+	}
+	identities, err := runAgeIdentityCommand(ctx, c.AgeIdentityCommand, timeout)
+	if err != nil {
+		return "", err
+	}
+
+	c.ageIdentityCommandIdentities = identities
+	return identities, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccAgeIdentityCommandConfig(command string) string {
+	return fmt.Sprintf(`
+provider "sops" {
+  age_identity_command = %s
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "command-value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+
+data "sops_decrypt" "again" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, command, testAgePublicKey)
+}
+
+func TestAccDecrypt_AgeIdentityCommand(t *testing.T) {
+	// An ambient key must not be what makes decryption succeed.
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAgeIdentityCommandConfig(fmt.Sprintf(`["echo", %q]`, testAgeSecretKey)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "command-value"),
+					resource.TestCheckResourceAttr("data.sops_decrypt.again", "output.secret", "command-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_AgeIdentityCommandFails(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAgeIdentityCommandConfig(`["sh", "-c", "echo vault is sealed >&2; exit 1"]`),
+				ExpectError: regexp.MustCompile(`(?s)age identity command "sh" failed.*vault is sealed`),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_AgeIdentityCommandNoIdentity(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAgeIdentityCommandConfig(`["echo", "not-a-key"]`),
+				ExpectError: regexp.MustCompile(`printed\s+no\s+usable\s+identity`),
+			},
+		},
+	})
+}
+
+func TestAgeIdentityCommandOutputCached(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	config := &SopsProviderConfig{
+		AgeIdentityCommand: []string{"sh", "-c", fmt.Sprintf("echo run >> %q; echo %s", counter, testAgeSecretKey)},
+	}
+
+	for range 3 {
+		identities, err := config.ageIdentityCommandOutput(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(identities) != testAgeSecretKey {
+			t.Fatalf("unexpected identities %q", identities)
+		}
+	}
+
+	runs, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(runs), "run"); got != 1 {
+		t.Fatalf("command ran %d times, want 1", got)
+	}
+}
+
+func TestAgeIdentityCommandTimeout(t *testing.T) {
+	config := &SopsProviderConfig{
+		AgeIdentityCommand: []string{"sleep", "60"},
+		Sops:               &SopsBinary{Timeout: 200 * time.Millisecond},
+	}
+
+	start := time.Now()
+	_, err := config.ageIdentityCommandOutput(t.Context())
+	if err == nil || !strings.Contains(err.Error(), `age identity command "sleep" timed out after 200ms`) {
+		t.Fatalf("expected a timeout naming the command, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("timed out command took %s", elapsed)
+	}
+}
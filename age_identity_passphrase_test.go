@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const testAgeIdentityPassphrase = "correct horse battery staple"
+
+// testPassphraseIdentityFile writes testAgeSecretKey to a file encrypted the
+// way age -p does, optionally ASCII-armored, and returns its path.
+func testPassphraseIdentityFile(t *testing.T, armored bool) string {
+	t.Helper()
+
+	recipient, err := age.NewScryptRecipient(testAgeIdentityPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep the test fast; age -p uses a much higher work factor.
+	recipient.SetWorkFactor(10)
+
+	var buf bytes.Buffer
+	var out io.WriteCloser = nopWriteCloser{&buf}
+	if armored {
+		out = armor.NewWriter(&buf)
+	}
+
+	w, err := age.Encrypt(out, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, ageKeygenFileContent()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.txt.age")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func testAccAgeIdentityPassphraseConfig(identityPath, passphrase string) string {
+	return fmt.Sprintf(`
+provider "sops" {
+  age_identity_path       = %q
+  age_identity_passphrase = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "passphrase-value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, identityPath, passphrase, testAgePublicKey)
+}
+
+func TestAccDecrypt_AgeIdentityPassphrase(t *testing.T) {
+	for name, armored := range map[string]bool{"binary": false, "armored": true} {
+		t.Run(name, func(t *testing.T) {
+			identityPath := testPassphraseIdentityFile(t, armored)
+
+			resource.Test(t, resource.TestCase{
+				PreCheck:                 func() { testAccPreCheck(t) },
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccAgeIdentityPassphraseConfig(identityPath, testAgeIdentityPassphrase),
+						Check: resource.TestCheckResourceAttr(
+							"data.sops_decrypt.test", "output.secret", "passphrase-value",
+						),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccDecrypt_AgeIdentityPassphraseIncorrect(t *testing.T) {
+	identityPath := testPassphraseIdentityFile(t, false)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAgeIdentityPassphraseConfig(identityPath, "wrong passphrase"),
+				ExpectError: regexp.MustCompile(`incorrect\s+passphrase`),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_AgeIdentityPassphraseMissing(t *testing.T) {
+	identityPath := testPassphraseIdentityFile(t, false)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAgeIdentityPathConfig(identityPath),
+				ExpectError: regexp.MustCompile(`(?s)passphrase-protected.*age_identity_passphrase`),
+			},
+		},
+	})
+}
@@ -1,60 +1,258 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"os/exec"
 	"strings"
 
 	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"filippo.io/age/plugin"
 )
 
-func generateAgeKeyPair() (privateKey string, publicKey string, err error) {
-	identity, err := age.GenerateX25519Identity()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate age identity: %w", err)
+// ageIdentity is a parsed age identity. PublicKey is empty and Plugin holds
+// the plugin name for plugin identities.
+type ageIdentity struct {
+	PrivateKey string
+	PublicKey  string
+	KeyType    string
+	Plugin     string
+}
+
+// Age key types accepted by key_type. The hybrid type combines ML-KEM-768
+// with X25519 so that recorded ciphertexts stay safe against a future
+// quantum computer.
+const (
+	ageKeyTypeX25519         = "x25519"
+	ageKeyTypeMLKEM768X25519 = "mlkem768x25519"
+)
+
+var ageKeyTypes = []string{ageKeyTypeX25519, ageKeyTypeMLKEM768X25519}
+
+func generateAgeKeyPair(keyType string) (privateKey string, publicKey string, err error) {
+	switch keyType {
+	case ageKeyTypeX25519, "":
+		identity, err := age.GenerateX25519Identity()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate age identity: %w", err)
+		}
+		return identity.String(), identity.Recipient().String(), nil
+	case ageKeyTypeMLKEM768X25519:
+		identity, err := age.GenerateHybridIdentity()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate age hybrid identity: %w", err)
+		}
+		return identity.String(), identity.Recipient().String(), nil
+	default:
+		return "", "", fmt.Errorf("unsupported age key type %q", keyType)
 	}
+}
 
-	return identity.String(), identity.Recipient().String(), nil
+func parseAgeIdentityLine(line string) (ageIdentity, error) {
+	if strings.HasPrefix(line, "AGE-PLUGIN-") {
+		identity, err := plugin.NewIdentity(line, nil)
+		if err != nil {
+			return ageIdentity{}, fmt.Errorf("failed to parse age plugin identity: %w", err)
+		}
+		return ageIdentity{PrivateKey: identity.String(), Plugin: identity.Name()}, nil
+	}
+
+	if strings.HasPrefix(line, "AGE-SECRET-KEY-PQ-") {
+		identity, err := age.ParseHybridIdentity(line)
+		if err != nil {
+			return ageIdentity{}, fmt.Errorf("failed to parse age hybrid private key: %w", err)
+		}
+		return ageIdentity{
+			PrivateKey: identity.String(),
+			PublicKey:  identity.Recipient().String(),
+			KeyType:    ageKeyTypeMLKEM768X25519,
+		}, nil
+	}
+
+	identity, err := age.ParseX25519Identity(line)
+	if err != nil {
+		return ageIdentity{}, fmt.Errorf("failed to parse age private key: %w", err)
+	}
+	return ageIdentity{
+		PrivateKey: identity.String(),
+		PublicKey:  identity.Recipient().String(),
+		KeyType:    ageKeyTypeX25519,
+	}, nil
 }
 
-// parseAgeIdentity accepts a bare AGE-SECRET-KEY-1... string or age-keygen
-// file content, the same shapes SOPS accepts for identities.
-func parseAgeIdentity(privateKey string) (*age.X25519Identity, error) {
-	var identity *age.X25519Identity
+// parseAgeIdentities accepts bare AGE-SECRET-KEY-1..., AGE-SECRET-KEY-PQ-1...
+// or AGE-PLUGIN-... strings, one per line, or age-keygen file content, the
+// same shapes SOPS accepts for identities.
+func parseAgeIdentities(privateKeys string) ([]ageIdentity, error) {
+	var identities []ageIdentity
 
-	for _, line := range strings.Split(privateKey, "\n") {
+	for _, line := range strings.Split(privateKeys, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
-		// Plugin identity recipients cannot be derived offline.
-		if strings.HasPrefix(line, "AGE-PLUGIN-") {
-			return nil, fmt.Errorf("age plugin identities are not supported: the public key of a plugin identity cannot be derived from the identity string")
-		}
-
-		parsed, err := age.ParseX25519Identity(line)
+		identity, err := parseAgeIdentityLine(line)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse age private key: %w", err)
+			return nil, err
 		}
+		identities = append(identities, identity)
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age private key found in input")
+	}
+
+	return identities, nil
+}
+
+// parseAgeIdentity is parseAgeIdentities for callers that model a single key
+// pair.
+func parseAgeIdentity(privateKey string) (ageIdentity, error) {
+	identities, err := parseAgeIdentities(privateKey)
+	if err != nil {
+		return ageIdentity{}, err
+	}
+
+	if len(identities) > 1 {
+		return ageIdentity{}, fmt.Errorf("multiple age identities found; expected exactly one")
+	}
+
+	return identities[0], nil
+}
 
-		if identity != nil {
-			return nil, fmt.Errorf("multiple age identities found; expected exactly one")
+// deriveAgePublicKeys returns the recipient of every identity in privateKeys,
+// in order. Plugin identities, whose recipient cannot be derived without
+// running the plugin, are left out and counted in unavailable.
+func deriveAgePublicKeys(privateKeys string) (publicKeys []string, unavailable int, err error) {
+	identities, err := parseAgeIdentities(privateKeys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	publicKeys = []string{}
+	for _, identity := range identities {
+		if identity.PublicKey == "" {
+			unavailable++
+			continue
 		}
-		identity = parsed
+		publicKeys = append(publicKeys, identity.PublicKey)
 	}
 
-	if identity == nil {
-		return nil, fmt.Errorf("no age private key found in input")
+	return publicKeys, unavailable, nil
+}
+
+// isEncryptedAgeFile reports whether content is an age-encrypted file, such
+// as an identity file protected with age -p, in binary or armored form.
+func isEncryptedAgeFile(content []byte) bool {
+	return bytes.HasPrefix(content, []byte("age-encryption.org/")) ||
+		bytes.HasPrefix(bytes.TrimSpace(content), []byte(armor.Header))
+}
+
+// decryptAgeIdentityFile unwraps a passphrase-protected identity file in
+// memory and returns its plaintext content.
+func decryptAgeIdentityFile(content []byte, passphrase string) (string, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return "", fmt.Errorf("invalid age identity passphrase: %w", err)
 	}
 
-	return identity, nil
+	var ciphertext io.Reader = bytes.NewReader(content)
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte(armor.Header)) {
+		ciphertext = armor.NewReader(ciphertext)
+	}
+
+	plaintext, err := age.Decrypt(ciphertext, identity)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt age identity file: %w", err)
+	}
+
+	identities, err := io.ReadAll(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt age identity file: %w", err)
+	}
+
+	return string(identities), nil
 }
 
-func deriveAgePublicKey(privateKey string) (string, error) {
-	identity, err := parseAgeIdentity(privateKey)
+// agePluginRecipientName returns the plugin name of an age1name1...
+// recipient, or "" for any other recipient.
+func agePluginRecipientName(recipient string) string {
+	if !strings.HasPrefix(recipient, "age1") {
+		return ""
+	}
+	if _, err := age.ParseX25519Recipient(recipient); err == nil {
+		return ""
+	}
+	// Hybrid recipients share the age1<name>1 shape of plugin recipients.
+	if _, err := age.ParseHybridRecipient(recipient); err == nil {
+		return ""
+	}
+
+	name, _, err := plugin.ParseRecipient(recipient)
 	if err != nil {
-		return "", err
+		return ""
+	}
+	return name
+}
+
+// agePluginIdentityNames returns the plugin names of the AGE-PLUGIN-...
+// identities in identity file content. Other lines are ignored.
+func agePluginIdentityNames(identities string) []string {
+	var names []string
+
+	for _, line := range strings.Split(identities, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "AGE-PLUGIN-") {
+			continue
+		}
+		if name, _, err := plugin.ParseIdentity(line); err == nil {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// requireAgePlugins checks that an age-plugin-<name> binary is on PATH for
+// each plugin, which is where age and SOPS look for them.
+func requireAgePlugins(names []string) error {
+	for _, name := range names {
+		if _, err := exec.LookPath("age-plugin-" + name); err != nil {
+			return fmt.Errorf("age plugin %q is required but age-plugin-%s was not found on PATH: %w", name, name, err)
+		}
+	}
+	return nil
+}
+
+// validateAgeRecipient accepts the recipient encodings SOPS understands for
+// age: native X25519 and hybrid post-quantum public keys, plugin recipients,
+// and ssh-ed25519 or ssh-rsa public keys.
+func validateAgeRecipient(recipient string) error {
+	if strings.HasPrefix(recipient, "ssh-") {
+		if _, err := agessh.ParseRecipient(recipient); err != nil {
+			return fmt.Errorf("failed to parse SSH public key: %w", err)
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(recipient, "age1pq1") {
+		if _, err := age.ParseHybridRecipient(recipient); err != nil {
+			return fmt.Errorf("failed to parse age hybrid public key: %w", err)
+		}
+		return nil
+	}
+
+	if agePluginRecipientName(recipient) != "" {
+		return nil
+	}
+
+	if _, err := age.ParseX25519Recipient(recipient); err != nil {
+		return fmt.Errorf("failed to parse age public key: %w", err)
 	}
 
-	return identity.Recipient().String(), nil
+	return nil
 }
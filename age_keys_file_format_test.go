@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
@@ -70,7 +69,7 @@ func TestAccAgePrivateKeyResource_ImportKeygenFileContent(t *testing.T) {
 	})
 }
 
-const testAgePluginIdentity = "AGE-PLUGIN-EXAMPLE-1QGQQQKQQYVQQKQQZQPEQQQQQZQQPJQQTQQPSQYQ"
+const testAgePluginIdentity = "AGE-PLUGIN-EXAMPLE-1WDHHQUEDW3JHXAPDWPK82EMFDCKKJER9DE6XJAREE7YMUP"
 
 func TestAccAgePublicKeyDataSource_PluginIdentity(t *testing.T) {
 	resource.Test(t, resource.TestCase{
@@ -83,7 +82,9 @@ data "sops_age_public_key" "test" {
   private_key = %q
 }
 `, testAgePluginIdentity),
-				ExpectError: regexp.MustCompile(`(?s)plugin identities are not\s+supported`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.sops_age_public_key.test", tfjsonpath.New("public_key"), knownvalue.Null()),
+				},
 			},
 		},
 	})
@@ -103,7 +104,9 @@ resource "sops_age_public_key" "test" {
   private_key_wo = %q
 }
 `, testAgePluginIdentity),
-				ExpectError: regexp.MustCompile(`(?s)plugin identities are not\s+supported`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("sops_age_public_key.test", tfjsonpath.New("public_key"), knownvalue.Null()),
+				},
 			},
 		},
 	})
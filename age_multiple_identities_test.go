@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func testAccMultipleIdentitiesConfig(providerConfig, recipient string) string {
+	return fmt.Sprintf(`
+provider "sops" {
+%s
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "rotation-value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, providerConfig, recipient)
+}
+
+func TestAccDecrypt_AgeIdentityFileWithSeveralKeys(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	content := fmt.Sprintf("# old key\n%s\n# new key\n%s\n", testAgeSecretKey, testAgeSecretKey2)
+	if err := os.WriteFile(keyFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, recipient := range []string{testAgePublicKey, testAgePublicKey2} {
+		t.Run(recipient, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				PreCheck:                 func() { testAccPreCheck(t) },
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccMultipleIdentitiesConfig(fmt.Sprintf("  age_identity_path = %q", keyFile), recipient),
+						Check:  resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "rotation-value"),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccDecrypt_AgeIdentityLists(t *testing.T) {
+	dir := t.TempDir()
+	oldKeyFile := filepath.Join(dir, "old.txt")
+	newKeyFile := filepath.Join(dir, "new.txt")
+	if err := os.WriteFile(oldKeyFile, []byte(testAgeSecretKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newKeyFile, []byte(testAgeSecretKey2+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"paths":  fmt.Sprintf("  age_identity_paths = [%q, %q]", oldKeyFile, newKeyFile),
+		"values": fmt.Sprintf("  age_identity_values = [%q, %q]", testAgeSecretKey, testAgeSecretKey2),
+		"mixed":  fmt.Sprintf("  age_identity_path   = %q\n  age_identity_values = [%q]", oldKeyFile, testAgeSecretKey2),
+	}
+
+	for name, providerConfig := range cases {
+		t.Run(name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				PreCheck:                 func() { testAccPreCheck(t) },
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccMultipleIdentitiesConfig(providerConfig, testAgePublicKey),
+						Check:  resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "rotation-value"),
+					},
+					{
+						Config: testAccMultipleIdentitiesConfig(providerConfig, testAgePublicKey2),
+						Check:  resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "rotation-value"),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccAgePublicKeyDataSource_SeveralIdentities(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "sops_age_public_key" "test" {
+  private_key = %q
+}
+`, fmt.Sprintf("%s\n%s\n%s\n", testAgeSecretKey, testAgePluginIdentity, testAgeSecretKey2)),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.sops_age_public_key.test",
+						tfjsonpath.New("public_key"),
+						knownvalue.StringExact(testAgePublicKey),
+					),
+					statecheck.ExpectKnownValue(
+						"data.sops_age_public_key.test",
+						tfjsonpath.New("public_keys"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact(testAgePublicKey),
+							knownvalue.StringExact(testAgePublicKey2),
+						}),
+					),
+				},
+			},
+		},
+	})
+}
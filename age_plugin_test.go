@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/plugin"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testAgeStubPlugin is the name of the stub plugin served by the test binary
+// itself. Its recipients and identities wrap plain X25519 keys.
+const testAgeStubPlugin = "sopstest"
+
+func TestMain(m *testing.M) {
+	if filepath.Base(os.Args[0]) == "age-plugin-"+testAgeStubPlugin {
+		os.Exit(runAgeStubPlugin())
+	}
+
+	os.Exit(m.Run())
+}
+
+func runAgeStubPlugin() int {
+	p, err := plugin.New(testAgeStubPlugin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	p.RegisterFlags(flag.NewFlagSet(testAgeStubPlugin, flag.ContinueOnError))
+	p.HandleRecipient(func(data []byte) (age.Recipient, error) {
+		return age.ParseX25519Recipient(string(data))
+	})
+	p.HandleIdentity(func(data []byte) (age.Identity, error) {
+		return age.ParseX25519Identity(string(data))
+	})
+
+	return p.Main()
+}
+
+// testAgeStubPluginPath puts the stub plugin on PATH by linking the test
+// binary under the plugin's executable name.
+func testAgeStubPluginPath(t *testing.T) {
+	t.Helper()
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Symlink(executable, filepath.Join(dir, "age-plugin-"+testAgeStubPlugin)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func testAgeStubPluginRecipient() string {
+	return plugin.EncodeRecipient(testAgeStubPlugin, []byte(testAgePublicKey))
+}
+
+func testAgeStubPluginIdentity() string {
+	return plugin.EncodeIdentity(testAgeStubPlugin, []byte(testAgeSecretKey))
+}
+
+func TestAccEncryptDecrypt_AgePlugin(t *testing.T) {
+	testAgeStubPluginPath(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+resource "sops_encrypt" "test" {
+  input = {
+    secret = "plugin-value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = sops_encrypt.test.output
+  input_type = "json"
+}
+`, testAgeStubPluginIdentity(), testAgeStubPluginRecipient()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "plugin-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_AgePluginIdentityNativeRecipient(t *testing.T) {
+	testAgeStubPluginPath(t)
+	encrypted := encryptFixture(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// The stub plugin unwraps native X25519 stanzas, like
+				// plugins that hold an X25519 key in hardware.
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_decrypt" "test" {
+  input      = %q
+  input_type = "json"
+}
+`, testAgeStubPluginIdentity(), encrypted),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.sops_decrypt.test", "output.%"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_AgePluginMissing(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+`, plugin.EncodeRecipient("missing", []byte(testAgePublicKey))),
+				ExpectError: regexp.MustCompile(`age-plugin-missing was not\s+found on PATH`),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_AgePluginMissing(t *testing.T) {
+	encrypted := encryptFixture(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_decrypt" "test" {
+  input      = %q
+  input_type = "json"
+}
+`, plugin.EncodeIdentity("missing", []byte(testAgeSecretKey)), encrypted),
+				ExpectError: regexp.MustCompile(`age-plugin-missing was not\s+found on PATH`),
+			},
+		},
+	})
+}
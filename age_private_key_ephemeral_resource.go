@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -18,6 +20,7 @@ func NewAgePrivateKeyEphemeralResource() ephemeral.EphemeralResource {
 type AgePrivateKeyEphemeralResource struct{}
 
 type AgePrivateKeyEphemeralResourceModel struct {
+	KeyType    types.String `tfsdk:"key_type"`
 	PrivateKey types.String `tfsdk:"private_key"`
 	PublicKey  types.String `tfsdk:"public_key"`
 }
@@ -28,16 +31,23 @@ func (r *AgePrivateKeyEphemeralResource) Metadata(_ context.Context, req ephemer
 
 func (r *AgePrivateKeyEphemeralResource) Schema(_ context.Context, _ ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Generates an age X25519 key pair without storing it in Terraform state. A new key pair is generated on every plan and apply, so the private key must be written to a write-only argument of a managed resource in the same configuration to be retained. To persist only the public key in state, pass the private key to the `sops_age_public_key` resource's write-only `private_key_wo` argument. Requires Terraform 1.10 or later; the `sops_age_public_key` and write-only-argument flow requires Terraform 1.11 or later.",
+		MarkdownDescription: "Generates an age key pair, X25519 by default, without storing it in Terraform state. A new key pair is generated on every plan and apply, so the private key must be written to a write-only argument of a managed resource in the same configuration to be retained. To persist only the public key in state, pass the private key to the `sops_age_public_key` resource's write-only `private_key_wo` argument. Requires Terraform 1.10 or later; the `sops_age_public_key` and write-only-argument flow requires Terraform 1.11 or later.",
 
 		Attributes: map[string]schema.Attribute{
+			"key_type": schema.StringAttribute{
+				MarkdownDescription: "Type of key pair to generate. Valid values are \"x25519\" and \"mlkem768x25519\", a hybrid post-quantum key that protects long-lived secrets against ciphertexts recorded today being decrypted by a future quantum computer. Hybrid keys use the `AGE-SECRET-KEY-PQ-1...` and `age1pq1...` formats. Defaults to \"x25519\".",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(ageKeyTypes...),
+				},
+			},
 			"private_key": schema.StringAttribute{
-				MarkdownDescription: "Generated age private key (identity) in `AGE-SECRET-KEY-1...` format.",
+				MarkdownDescription: "Generated age private key (identity) in `AGE-SECRET-KEY-1...` or `AGE-SECRET-KEY-PQ-1...` format.",
 				Computed:            true,
 				Sensitive:           true,
 			},
 			"public_key": schema.StringAttribute{
-				MarkdownDescription: "Corresponding age public key (recipient) in `age1...` format.",
+				MarkdownDescription: "Corresponding age public key (recipient) in `age1...` or `age1pq1...` format.",
 				Computed:            true,
 			},
 		},
@@ -45,7 +55,14 @@ func (r *AgePrivateKeyEphemeralResource) Schema(_ context.Context, _ ephemeral.S
 }
 
 func (r *AgePrivateKeyEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
-	privateKey, publicKey, err := generateAgeKeyPair()
+	var data AgePrivateKeyEphemeralResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	privateKey, publicKey, err := generateAgeKeyPair(data.KeyType.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Age Key Generation Failed",
@@ -54,10 +71,8 @@ func (r *AgePrivateKeyEphemeralResource) Open(ctx context.Context, req ephemeral
 		return
 	}
 
-	data := AgePrivateKeyEphemeralResourceModel{
-		PrivateKey: types.StringValue(privateKey),
-		PublicKey:  types.StringValue(publicKey),
-	}
+	data.PrivateKey = types.StringValue(privateKey)
+	data.PublicKey = types.StringValue(publicKey)
 
 	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
 }
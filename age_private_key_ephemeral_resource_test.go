@@ -47,13 +47,13 @@ func (c echoedAgeKeyPairMatchCheck) CheckState(ctx context.Context, req stateche
 			return
 		}
 
-		derived, err := deriveAgePublicKey(privateKey)
+		identity, err := parseAgeIdentity(privateKey)
 		if err != nil {
 			resp.Error = fmt.Errorf("generated private key is not parseable: %s", err)
 			return
 		}
 
-		if derived != publicKey {
+		if derived := identity.PublicKey; derived != publicKey {
 			resp.Error = fmt.Errorf("public_key %q does not match key derived from private_key %q", publicKey, derived)
 			return
 		}
@@ -102,3 +102,44 @@ resource "echo" "test" {}
 		},
 	})
 }
+
+func TestAccAgePrivateKeyEphemeralResource_Hybrid(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithEcho,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+ephemeral "sops_age_private_key" "test" {
+  key_type = "mlkem768x25519"
+}
+
+provider "echo" {
+  data = {
+    private_key = ephemeral.sops_age_private_key.test.private_key
+    public_key  = ephemeral.sops_age_private_key.test.public_key
+  }
+}
+
+resource "echo" "test" {}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"echo.test",
+						tfjsonpath.New("data").AtMapKey("private_key"),
+						knownvalue.StringRegexp(ageHybridPrivateKeyRegex),
+					),
+					statecheck.ExpectKnownValue(
+						"echo.test",
+						tfjsonpath.New("data").AtMapKey("public_key"),
+						knownvalue.StringRegexp(ageHybridPublicKeyRegex),
+					),
+					echoedAgeKeyPairMatchCheck{resourceAddress: "echo.test"},
+				},
+			},
+		},
+	})
+}
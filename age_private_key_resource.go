@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -20,6 +24,7 @@ func NewAgePrivateKeyResource() resource.Resource {
 type AgePrivateKeyResource struct{}
 
 type AgePrivateKeyResourceModel struct {
+	KeyType    types.String `tfsdk:"key_type"`
 	PrivateKey types.String `tfsdk:"private_key"`
 	PublicKey  types.String `tfsdk:"public_key"`
 }
@@ -30,16 +35,26 @@ func (r *AgePrivateKeyResource) Metadata(ctx context.Context, req resource.Metad
 
 func (r *AgePrivateKeyResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Generates an age X25519 key pair. The private key is stored **unencrypted** in the Terraform state; use the `sops_age_private_key` ephemeral resource if the private key should not be persisted. Regenerate the key pair with `terraform apply -replace`. An existing key can be adopted with `terraform import` using the private key as the import ID.",
+		MarkdownDescription: "Generates an age key pair, X25519 by default. The private key is stored **unencrypted** in the Terraform state; use the `sops_age_private_key` ephemeral resource if the private key should not be persisted. Regenerate the key pair with `terraform apply -replace`. An existing key can be adopted with `terraform import` using the private key as the import ID.",
 
 		Attributes: map[string]schema.Attribute{
+			"key_type": schema.StringAttribute{
+				MarkdownDescription: "Type of key pair to generate. Valid values are \"x25519\" and \"mlkem768x25519\", a hybrid post-quantum key that protects long-lived secrets against ciphertexts recorded today being decrypted by a future quantum computer. Hybrid keys use the `AGE-SECRET-KEY-PQ-1...` and `age1pq1...` formats. Defaults to \"x25519\".",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(ageKeyTypes...),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
 			"private_key": schema.StringAttribute{
-				MarkdownDescription: "Generated age private key (identity) in `AGE-SECRET-KEY-1...` format.",
+				MarkdownDescription: "Generated age private key (identity) in `AGE-SECRET-KEY-1...` or `AGE-SECRET-KEY-PQ-1...` format.",
 				Computed:            true,
 				Sensitive:           true,
 			},
 			"public_key": schema.StringAttribute{
-				MarkdownDescription: "Corresponding age public key (recipient) in `age1...` format.",
+				MarkdownDescription: "Corresponding age public key (recipient) in `age1...` or `age1pq1...` format.",
 				Computed:            true,
 			},
 		},
@@ -47,7 +62,14 @@ func (r *AgePrivateKeyResource) Schema(ctx context.Context, req resource.SchemaR
 }
 
 func (r *AgePrivateKeyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	privateKey, publicKey, err := generateAgeKeyPair()
+	var data AgePrivateKeyResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	privateKey, publicKey, err := generateAgeKeyPair(data.KeyType.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Age Key Generation Failed",
@@ -56,10 +78,8 @@ func (r *AgePrivateKeyResource) Create(ctx context.Context, req resource.CreateR
 		return
 	}
 
-	data := AgePrivateKeyResourceModel{
-		PrivateKey: types.StringValue(privateKey),
-		PublicKey:  types.StringValue(publicKey),
-	}
+	data.PrivateKey = types.StringValue(privateKey)
+	data.PublicKey = types.StringValue(publicKey)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -82,11 +102,24 @@ func (r *AgePrivateKeyResource) ImportState(ctx context.Context, req resource.Im
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Invalid Age Private Key",
-			fmt.Sprintf("The import ID must be an age private key in AGE-SECRET-KEY-1... format or age-keygen file content. %s", err),
+			fmt.Sprintf("The import ID must be an age private key in AGE-SECRET-KEY-1... or AGE-SECRET-KEY-PQ-1... format or age-keygen file content. %s", err),
 		)
 		return
 	}
 
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("private_key"), identity.String())...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("public_key"), identity.Recipient().String())...)
+	if identity.Plugin != "" {
+		resp.Diagnostics.AddError(
+			"Invalid Age Private Key",
+			fmt.Sprintf("The import ID is an identity for the age plugin %q. Only native age private keys can be imported, since this resource stores the derived public key.", identity.Plugin),
+		)
+		return
+	}
+
+	// Leave key_type null for X25519 keys, matching configurations that
+	// omit it.
+	if identity.KeyType != ageKeyTypeX25519 {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("key_type"), identity.KeyType)...)
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("private_key"), identity.PrivateKey)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("public_key"), identity.PublicKey)...)
 }
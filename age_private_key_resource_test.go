@@ -16,6 +16,8 @@ import (
 
 var agePrivateKeyRegex = regexp.MustCompile(`^AGE-SECRET-KEY-1[0-9A-Z]+$`)
 var agePublicKeyRegex = regexp.MustCompile(`^age1[0-9a-z]+$`)
+var ageHybridPrivateKeyRegex = regexp.MustCompile(`^AGE-SECRET-KEY-PQ-1[0-9A-Z]+$`)
+var ageHybridPublicKeyRegex = regexp.MustCompile(`^age1pq1[0-9a-z]+$`)
 
 func TestAccAgePrivateKeyResource_Basic(t *testing.T) {
 	resource.Test(t, resource.TestCase{
@@ -46,6 +48,80 @@ func TestAccAgePrivateKeyResource_Basic(t *testing.T) {
 	})
 }
 
+func TestAccAgePrivateKeyResource_Hybrid(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "sops_age_private_key" "test" {
+  key_type = "mlkem768x25519"
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"sops_age_private_key.test",
+						tfjsonpath.New("private_key"),
+						knownvalue.StringRegexp(ageHybridPrivateKeyRegex),
+					),
+					statecheck.ExpectKnownValue(
+						"sops_age_private_key.test",
+						tfjsonpath.New("public_key"),
+						knownvalue.StringRegexp(ageHybridPublicKeyRegex),
+					),
+				},
+				Check: testAccCheckAgeKeyPairMatches("sops_age_private_key.test"),
+			},
+		},
+	})
+}
+
+func TestAccAgePrivateKeyResource_HybridEncryptRoundTrip(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "sops_age_private_key" "test" {
+  key_type = "mlkem768x25519"
+}
+
+resource "sops_encrypt" "test" {
+  input = {
+    secret = "hybrid-value"
+  }
+  age_recipients = [sops_age_private_key.test.public_key]
+}
+`,
+				Check: testAccCheckAgeKeyDecrypts(
+					"sops_age_private_key.test",
+					"sops_encrypt.test",
+					`"secret": "hybrid-value"`,
+				),
+			},
+		},
+	})
+}
+
+func TestAccAgePrivateKeyResource_InvalidKeyType(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "sops_age_private_key" "test" {
+  key_type = "ed25519"
+}
+`,
+				ExpectError: regexp.MustCompile(`(?s)value must be one of`),
+			},
+		},
+	})
+}
+
 func TestAccAgePrivateKeyResource_EncryptRoundTrip(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
@@ -102,6 +178,47 @@ func TestAccAgePrivateKeyResource_Import(t *testing.T) {
 	})
 }
 
+func TestAccAgePrivateKeyResource_ImportHybrid(t *testing.T) {
+	privateKey, publicKey, err := generateAgeKeyPair(ageKeyTypeMLKEM768X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := `
+resource "sops_age_private_key" "test" {
+  key_type = "mlkem768x25519"
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+			},
+			{
+				Config:        config,
+				ResourceName:  "sops_age_private_key.test",
+				ImportState:   true,
+				ImportStateId: privateKey,
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 {
+						return fmt.Errorf("expected 1 imported instance, got %d", len(states))
+					}
+					if got := states[0].Attributes["key_type"]; got != ageKeyTypeMLKEM768X25519 {
+						return fmt.Errorf("imported key_type = %q, want %q", got, ageKeyTypeMLKEM768X25519)
+					}
+					if got := states[0].Attributes["public_key"]; got != publicKey {
+						return fmt.Errorf("imported public_key = %q, want %q", got, publicKey)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
 func TestAccAgePrivateKeyResource_ImportInvalidKey(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
@@ -131,12 +248,12 @@ func testAccCheckAgeKeyPairMatches(resourceName string) resource.TestCheckFunc {
 		privateKey := rs.Primary.Attributes["private_key"]
 		publicKey := rs.Primary.Attributes["public_key"]
 
-		derived, err := deriveAgePublicKey(privateKey)
+		identity, err := parseAgeIdentity(privateKey)
 		if err != nil {
 			return fmt.Errorf("generated private key is not parseable: %s", err)
 		}
 
-		if derived != publicKey {
+		if derived := identity.PublicKey; derived != publicKey {
 			return fmt.Errorf("public_key %q does not match key derived from private_key %q", publicKey, derived)
 		}
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -22,6 +23,7 @@ type AgePublicKeyDataSource struct{}
 type AgePublicKeyDataSourceModel struct {
 	PrivateKey types.String `tfsdk:"private_key"`
 	PublicKey  types.String `tfsdk:"public_key"`
+	PublicKeys types.List   `tfsdk:"public_keys"`
 }
 
 func (d *AgePublicKeyDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -34,7 +36,7 @@ func (d *AgePublicKeyDataSource) Schema(ctx context.Context, req datasource.Sche
 
 		Attributes: map[string]schema.Attribute{
 			"private_key": schema.StringAttribute{
-				MarkdownDescription: "Age private key (identity) in `AGE-SECRET-KEY-1...` format.",
+				MarkdownDescription: "Age private key (identity) in `AGE-SECRET-KEY-1...` or `AGE-SECRET-KEY-PQ-1...` format, or an `AGE-PLUGIN-...` plugin identity. May hold several identities, one per line, as in an age-keygen `keys.txt` file.",
 				Required:            true,
 				Sensitive:           true,
 				Validators: []validator.String{
@@ -42,7 +44,12 @@ func (d *AgePublicKeyDataSource) Schema(ctx context.Context, req datasource.Sche
 				},
 			},
 			"public_key": schema.StringAttribute{
-				MarkdownDescription: "Derived age public key (recipient) in `age1...` format.",
+				MarkdownDescription: "Derived age public key (recipient) in `age1...` or `age1pq1...` format. When `private_key` holds several identities, this is the recipient of the first one. Null for plugin identities, whose recipient cannot be derived without the plugin.",
+				Computed:            true,
+			},
+			"public_keys": schema.ListAttribute{
+				MarkdownDescription: "Derived public keys (recipients) of every identity in `private_key`, in order. Plugin identities are left out.",
+				ElementType:         types.StringType,
 				Computed:            true,
 			},
 		},
@@ -57,7 +64,7 @@ func (d *AgePublicKeyDataSource) Read(ctx context.Context, req datasource.ReadRe
 		return
 	}
 
-	publicKey, err := deriveAgePublicKey(data.PrivateKey.ValueString())
+	publicKeys, unavailable, err := deriveAgePublicKeys(data.PrivateKey.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("private_key"),
@@ -67,7 +74,26 @@ func (d *AgePublicKeyDataSource) Read(ctx context.Context, req datasource.ReadRe
 		return
 	}
 
-	data.PublicKey = types.StringValue(publicKey)
+	if unavailable > 0 {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("private_key"),
+			"Age Public Key Unavailable",
+			fmt.Sprintf("%d age plugin identities were left out of the public keys, since their recipient cannot be derived without running the plugin. "+
+				"Use the recipient printed by the age plugin instead.", unavailable),
+		)
+	}
+
+	data.PublicKey = types.StringNull()
+	if len(publicKeys) > 0 {
+		data.PublicKey = types.StringValue(publicKeys[0])
+	}
+
+	var diags diag.Diagnostics
+	data.PublicKeys, diags = types.ListValueFrom(ctx, types.StringType, publicKeys)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -38,6 +38,34 @@ data "sops_age_public_key" "test" {
 	})
 }
 
+func TestAccAgePublicKeyDataSource_Hybrid(t *testing.T) {
+	privateKey, publicKey, err := generateAgeKeyPair(ageKeyTypeMLKEM768X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "sops_age_public_key" "test" {
+  private_key = %q
+}
+`, privateKey),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.sops_age_public_key.test",
+						tfjsonpath.New("public_key"),
+						knownvalue.StringExact(publicKey),
+					),
+				},
+			},
+		},
+	})
+}
+
 func TestAccAgePublicKeyDataSource_InvalidPrivateKey(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
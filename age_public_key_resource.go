@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
@@ -26,6 +28,7 @@ type AgePublicKeyResourceModel struct {
 	PrivateKeyWo        types.String `tfsdk:"private_key_wo"`
 	PrivateKeyWoVersion types.Int64  `tfsdk:"private_key_wo_version"`
 	PublicKey           types.String `tfsdk:"public_key"`
+	PublicKeys          types.List   `tfsdk:"public_keys"`
 }
 
 func (r *AgePublicKeyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -38,7 +41,7 @@ func (r *AgePublicKeyResource) Schema(ctx context.Context, req resource.SchemaRe
 
 		Attributes: map[string]schema.Attribute{
 			"private_key_wo": schema.StringAttribute{
-				MarkdownDescription: "Age private key (identity) in `AGE-SECRET-KEY-1...` format. Write-only: never persisted in state or plan. Because Terraform cannot detect changes to write-only values, increment `private_key_wo_version` when supplying a different key.",
+				MarkdownDescription: "Age private key (identity) in `AGE-SECRET-KEY-1...` or `AGE-SECRET-KEY-PQ-1...` format, or an `AGE-PLUGIN-...` plugin identity. May hold several identities, one per line, as in an age-keygen `keys.txt` file. Write-only: never persisted in state or plan. Because Terraform cannot detect changes to write-only values, increment `private_key_wo_version` when supplying a different key.",
 				Required:            true,
 				WriteOnly:           true,
 				Sensitive:           true,
@@ -61,12 +64,20 @@ func (r *AgePublicKeyResource) Schema(ctx context.Context, req resource.SchemaRe
 				},
 			},
 			"public_key": schema.StringAttribute{
-				MarkdownDescription: "Derived age public key (recipient) in `age1...` format.",
+				MarkdownDescription: "Derived age public key (recipient) in `age1...` or `age1pq1...` format. When `private_key_wo` holds several identities, this is the recipient of the first one. Null for plugin identities, whose recipient cannot be derived without the plugin.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"public_keys": schema.ListAttribute{
+				MarkdownDescription: "Derived public keys (recipients) of every identity in `private_key_wo`, in order. Plugin identities are left out.",
+				ElementType:         types.StringType,
+				Computed:            true,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
 		},
 	}
 }
@@ -95,7 +106,7 @@ func (r *AgePublicKeyResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
-	publicKey, err := deriveAgePublicKey(privateKey.ValueString())
+	publicKeys, unavailable, err := deriveAgePublicKeys(privateKey.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("private_key_wo"),
@@ -105,7 +116,26 @@ func (r *AgePublicKeyResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
-	data.PublicKey = types.StringValue(publicKey)
+	if unavailable > 0 {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("private_key_wo"),
+			"Age Public Key Unavailable",
+			fmt.Sprintf("%d age plugin identities were left out of the public keys, since their recipient cannot be derived without running the plugin. "+
+				"Use the recipient printed by the age plugin instead.", unavailable),
+		)
+	}
+
+	data.PublicKey = types.StringNull()
+	if len(publicKeys) > 0 {
+		data.PublicKey = types.StringValue(publicKeys[0])
+	}
+
+	var diags diag.Diagnostics
+	data.PublicKeys, diags = types.ListValueFrom(ctx, types.StringType, publicKeys)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -121,6 +151,23 @@ func (r *AgePublicKeyResource) Update(ctx context.Context, req resource.UpdateRe
 		return
 	}
 
+	// State written before public_keys existed has nothing to carry over;
+	// the write-only key is not available here, so rebuild the list from the
+	// stored public key.
+	if data.PublicKeys.IsUnknown() {
+		publicKeys := []string{}
+		if !data.PublicKey.IsNull() && !data.PublicKey.IsUnknown() {
+			publicKeys = append(publicKeys, data.PublicKey.ValueString())
+		}
+
+		var diags diag.Diagnostics
+		data.PublicKeys, diags = types.ListValueFrom(ctx, types.StringType, publicKeys)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHKeyPair writes a throwaway unencrypted ssh-ed25519 private key and
+// returns its path and authorized_keys public key line.
+func testSSHKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, "sops-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keyPath, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey)))
+}
+
+func TestAccEncryptDecrypt_SSHKey(t *testing.T) {
+	keyPath, publicKey := testSSHKeyPair(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_ssh_private_key_path = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "ssh-value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, keyPath, publicKey+" engineer@example.com"),
+				Check: resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "ssh-value"),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_SSHKeyPathMissingFile(t *testing.T) {
+	encrypted := encryptFixture(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_ssh_private_key_path = "~/definitely-not-here/id_ed25519"
+}
+
+data "sops_decrypt" "test" {
+  input      = %q
+  input_type = "json"
+}
+`, encrypted),
+				ExpectError: regexp.MustCompile("SSH private key file not found"),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_InvalidAgeRecipient(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = ["ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTY="]
+}
+`,
+				ExpectError: regexp.MustCompile("Invalid Age Recipient"),
+			},
+		},
+	})
+}
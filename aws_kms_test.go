@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testAccKMSPreCheck requires a KMS endpoint, such as local-kms, with an
+// existing key. Credentials come from the usual AWS environment variables.
+func testAccKMSPreCheck(t *testing.T) (string, string) {
+	t.Helper()
+	testAccPreCheck(t)
+
+	endpoint := os.Getenv("SOPS_TEST_KMS_ENDPOINT")
+	arn := os.Getenv("SOPS_TEST_KMS_ARN")
+	if endpoint == "" || arn == "" {
+		t.Skip("SOPS_TEST_KMS_ENDPOINT and SOPS_TEST_KMS_ARN must be set for AWS KMS tests")
+	}
+
+	return endpoint, arn
+}
+
+func TestAccEncryptDecrypt_KMS(t *testing.T) {
+	endpoint, arn := testAccKMSPreCheck(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  aws_region       = "us-east-1"
+  aws_kms_endpoint = %q
+}
+
+resource "sops_encrypt" "test" {
+  input = {
+    secret = "kms-value"
+  }
+  kms_arns = [%q]
+  kms_encryption_context = {
+    team = "platform"
+  }
+}
+
+data "sops_decrypt" "test" {
+  input      = sops_encrypt.test.output
+  input_type = "json"
+}
+`, endpoint, arn),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "kms-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_InvalidKMSARN(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  kms_arns = ["alias/sops"]
+}
+`,
+				ExpectError: regexp.MustCompile("must be an AWS KMS key or alias ARN"),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_KMSRoleRequiresARNs(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+  kms_role       = "arn:aws:iam::123456789012:role/sops"
+}
+`, testAgePublicKey),
+				ExpectError: regexp.MustCompile(`(?s)Attribute "kms_arns" must be specified`),
+			},
+		},
+	})
+}
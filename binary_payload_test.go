@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccEncryptDecrypt_BinaryText(t *testing.T) {
+	kubeconfig := "apiVersion: v1\nkind: Config\nusers:\n- name: admin\n  user:\n    token: hunter2\n"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+resource "sops_encrypt" "kubeconfig" {
+  input          = %q
+  input_type     = "binary"
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "kubeconfig" {
+  input       = sops_encrypt.kubeconfig.output
+  input_type  = "json"
+  output_type = "binary"
+}
+`, testAgeSecretKey, kubeconfig, testAgePublicKey),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckFieldIsEncrypted("sops_encrypt.kubeconfig", "data"),
+					resource.TestCheckResourceAttr("data.sops_decrypt.kubeconfig", "output", kubeconfig),
+					resource.TestCheckResourceAttr("data.sops_decrypt.kubeconfig", "output_base64", base64.StdEncoding.EncodeToString([]byte(kubeconfig))),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDecrypt_BinaryBase64(t *testing.T) {
+	// Not valid UTF-8, so only output_base64 can hold it.
+	payload := base64.StdEncoding.EncodeToString([]byte{0x00, 0xff, 0xfe, 0x01, 'k', 'e', 'y'})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_encrypt" "keystore" {
+  input_base64   = %q
+  age_recipients = [%q]
+  output_type    = "yaml"
+}
+
+data "sops_decrypt" "keystore" {
+  input       = data.sops_encrypt.keystore.output
+  input_type  = "yaml"
+  output_type = "binary"
+}
+`, testAgeSecretKey, payload, testAgePublicKey),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("data.sops_encrypt.keystore", "output", regexp.MustCompile(`(?m)^data: ENC\[`)),
+					resource.TestCheckResourceAttr("data.sops_decrypt.keystore", "output_base64", payload),
+					resource.TestCheckNoResourceAttr("data.sops_decrypt.keystore", "output"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_BinaryInputType(t *testing.T) {
+	encrypted, err := encryptBinaryWithSops(t.Context(), []byte("-----BEGIN CERTIFICATE-----\n"), SopsEncryptOptions{
+		AgeRecipients: []string{testAgePublicKey},
+	})
+	if err != nil {
+		t.Fatalf("failed to build encrypted fixture: %s", err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// As with the sops CLI, input_type "binary" reads the JSON
+				// document and returns the payload.
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_decrypt" "cert" {
+  input      = %q
+  input_type = "binary"
+}
+`, testAgeSecretKey, encrypted),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.cert", "output", "-----BEGIN CERTIFICATE-----\n"),
+					resource.TestCheckResourceAttrSet("data.sops_decrypt.cert", "output_base64"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncrypt_BinaryInputValidation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body string
+		want string
+	}{
+		"object_input": {
+			body: `
+  input      = { secret = "value" }
+  input_type = "binary"`,
+			want: `Input\s+must\s+be\s+a\s+string\s+when\s+input_type\s+is\s+"binary"`,
+		},
+		"base64_with_json": {
+			body: `
+  input_base64 = "aGVsbG8="
+  input_type   = "json"`,
+			want: `input_base64\s+can\s+only\s+be\s+encrypted\s+with\s+input_type\s+"binary"`,
+		},
+		"both_inputs": {
+			body: `
+  input        = "hello"
+  input_base64 = "aGVsbG8="`,
+			want: `Exactly\s+one\s+of\s+input\s+and\s+input_base64`,
+		},
+		"no_input": {
+			body: `
+  input_type = "binary"`,
+			want: `Exactly\s+one\s+of\s+input\s+and\s+input_base64`,
+		},
+		"invalid_base64": {
+			body: `
+  input_base64 = "not base64!"`,
+			want: `Invalid\s+Base64`,
+		},
+		"ini_output": {
+			body: `
+  input_base64 = "aGVsbG8="
+  output_type  = "ini"`,
+			want: `Binary\s+input\s+is\s+stored\s+as\s+a\s+single\s+"data"\s+string`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				PreCheck:                 func() { testAccPreCheck(t) },
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+resource "sops_encrypt" "test" {
+%s
+  age_recipients = [%q]
+}
+`, tc.body, testAgePublicKey),
+						ExpectError: regexp.MustCompile(tc.want),
+					},
+				},
+			})
+		})
+	}
+}
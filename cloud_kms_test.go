@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccEncryptDecrypt_GCPKMS(t *testing.T) {
+	resourceID := os.Getenv("SOPS_TEST_GCP_KMS_RESOURCE_ID")
+	if resourceID == "" {
+		t.Skip("SOPS_TEST_GCP_KMS_RESOURCE_ID must be set for GCP KMS tests")
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  gcp_credentials = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "gcp-value"
+  }
+  gcp_kms_resource_ids = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, os.Getenv("SOPS_TEST_GCP_CREDENTIALS"), resourceID),
+				Check: resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "gcp-value"),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDecrypt_AzureKeyVault(t *testing.T) {
+	keyURL := os.Getenv("SOPS_TEST_AZURE_KV_URL")
+	if keyURL == "" {
+		t.Skip("SOPS_TEST_AZURE_KV_URL must be set for Azure Key Vault tests")
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "sops_encrypt" "test" {
+  input = {
+    secret = "azure-value"
+  }
+  azure_kv_urls = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, keyURL),
+				Check: resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "azure-value"),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_InvalidCloudKMSKeys(t *testing.T) {
+	testCases := map[string]struct {
+		attribute string
+		value     string
+		want      string
+	}{
+		"gcp":   {"gcp_kms_resource_ids", "projects/p/keyRings/r/cryptoKeys/k", "must be a GCP KMS crypto key resource ID"},
+		"azure": {"azure_kv_urls", "https://my-vault.vault.azure.net/secrets/k", "must be an Azure Key Vault key URL"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				PreCheck:                 func() { testAccPreCheck(t) },
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  %s = [%q]
+}
+`, tc.attribute, tc.value),
+						ExpectError: regexp.MustCompile(tc.want),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestEncryptWithSopsCloudKMSEnvironment(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), "env")
+	sops, err := probeSopsBinary(t.Context(), testFakeSopsBinary(t, "3.13.0", fmt.Sprintf("env > %q\necho '{}'", envPath)), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = encryptWithSops(t.Context(), map[string]interface{}{"secret": "value"}, SopsEncryptOptions{
+		GCPKMSResourceIDs: []string{"projects/p/locations/global/keyRings/r/cryptoKeys/k"},
+		AzureKVURLs:       []string{"https://my-vault.vault.azure.net/keys/k/0123456789abcdef"},
+		Backend: SopsBackendOptions{
+			Sops:               sops,
+			GCPCredentials:     "/etc/gcp/key.json",
+			GCPAccessToken:     "ya29.fake-token",
+			GCPKMSEndpoint:     "localhost:9010",
+			AzureTenantID:      "tenant",
+			AzureClientID:      "client",
+			AzureClientSecret:  "client-secret",
+			AzureAuthorityHost: "http://localhost:9020/",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := os.ReadFile(envPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"GOOGLE_CREDENTIALS=/etc/gcp/key.json",
+		"GOOGLE_OAUTH_ACCESS_TOKEN=ya29.fake-token",
+		"SOPS_GCP_KMS_ENDPOINT=localhost:9010",
+		"AZURE_TENANT_ID=tenant",
+		"AZURE_CLIENT_ID=client",
+		"AZURE_CLIENT_SECRET=client-secret",
+		"AZURE_AUTHORITY_HOST=http://localhost:9020/",
+	} {
+		if !strings.Contains("\n"+string(env), "\n"+want+"\n") {
+			t.Errorf("sops environment is missing %s", want)
+		}
+	}
+}
+
+func TestDecryptWithSopsGCPKMSEndpointRequiresSops313(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), "env")
+	sops, err := probeSopsBinary(t.Context(), testFakeSopsBinary(t, "3.12.1", fmt.Sprintf("env > %q", envPath)), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = decryptWithSops(t.Context(), []byte("{}"), SopsDecryptOptions{
+		InputType: "json",
+		Backend:   SopsBackendOptions{Sops: sops, GCPKMSEndpoint: "localhost:9010"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "GCP KMS endpoint overrides require sops 3.13.0 or later") {
+		t.Fatalf("expected a sops version error, got %v", err)
+	}
+	if _, err := os.Stat(envPath); !os.IsNotExist(err) {
+		t.Fatal("sops ran despite being too old")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// decryptCache memoizes decryptions for the lifetime of a provider instance,
+// so a secrets file read from many modules is only decrypted once. Identical
+// decryptions running at the same time share a single sops process.
+type decryptCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*decryptCacheEntry
+}
+
+type decryptCacheEntry struct {
+	done      chan struct{}
+	plaintext []byte
+	err       error
+
+	// waiters counts the callers waiting for done, and cancel stops sops
+	// once the last of them has given up.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// decryptCaches holds every cache created in this process so main can wipe
+// the plaintext they hold when the provider shuts down.
+var (
+	decryptCachesMu sync.Mutex
+	decryptCaches   []*decryptCache
+)
+
+func newDecryptCache() *decryptCache {
+	c := &decryptCache{entries: map[[sha256.Size]byte]*decryptCacheEntry{}}
+
+	decryptCachesMu.Lock()
+	defer decryptCachesMu.Unlock()
+	decryptCaches = append(decryptCaches, c)
+
+	return c
+}
+
+// release wipes a cache that Configure has replaced and stops tracking it.
+func (c *decryptCache) release() {
+	decryptCachesMu.Lock()
+	decryptCaches = slices.DeleteFunc(decryptCaches, func(other *decryptCache) bool { return other == c })
+	decryptCachesMu.Unlock()
+
+	c.wipe()
+}
+
+// wipeDecryptCaches overwrites and drops every cached plaintext.
+func wipeDecryptCaches() {
+	decryptCachesMu.Lock()
+	defer decryptCachesMu.Unlock()
+
+	for _, c := range decryptCaches {
+		c.wipe()
+	}
+	decryptCaches = nil
+}
+
+func (c *decryptCache) wipe() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.entries {
+		select {
+		case <-entry.done:
+			clear(entry.plaintext)
+		default:
+		}
+	}
+	c.entries = map[[sha256.Size]byte]*decryptCacheEntry{}
+}
+
+// decryptCacheKey digests the ciphertext together with every option that
+// affects the result, including the identities, so a reader with a different
+// identity never sees a plaintext it could not decrypt itself.
+func decryptCacheKey(encryptedData []byte, opts SopsDecryptOptions) ([sha256.Size]byte, error) {
+	encoded, err := json.Marshal(struct {
+		Input   []byte
+		Options SopsDecryptOptions
+	}{encryptedData, opts})
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to compute decryption cache key: %w", err)
+	}
+	return sha256.Sum256(encoded), nil
+}
+
+// decrypt returns a cached plaintext or decrypts with sops. Failures are not
+// cached. The returned slice is shared and must not be modified.
+//
+// Callers asking for the same decryption share one sops process, which runs
+// until it finishes or every waiting caller has given up. Each caller returns
+// as soon as its own ctx is done, without failing the others.
+func (c *decryptCache) decrypt(ctx context.Context, encryptedData []byte, opts SopsDecryptOptions) ([]byte, error) {
+	if c == nil {
+		return decryptWithSops(ctx, encryptedData, opts)
+	}
+
+	key, err := decryptCacheKey(encryptedData, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		fillCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		entry = &decryptCacheEntry{done: make(chan struct{}), cancel: cancel}
+		c.entries[key] = entry
+		go c.fill(fillCtx, key, entry, encryptedData, opts)
+	}
+	entry.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		c.leave(key, entry)
+		return entry.plaintext, entry.err
+	case <-ctx.Done():
+		c.leave(key, entry)
+		return nil, ctx.Err()
+	}
+}
+
+// leave drops a caller waiting on entry. Once none are left before sops has
+// finished, sops is cancelled and the entry forgotten, so no later caller
+// sees the cancellation.
+func (c *decryptCache) leave(key [sha256.Size]byte, entry *decryptCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.waiters--
+	if entry.waiters > 0 {
+		return
+	}
+	select {
+	case <-entry.done:
+	default:
+		entry.cancel()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// fill decrypts into entry and drops it from the cache if sops failed.
+func (c *decryptCache) fill(ctx context.Context, key [sha256.Size]byte, entry *decryptCacheEntry, encryptedData []byte, opts SopsDecryptOptions) {
+	entry.plaintext, entry.err = decryptWithSops(ctx, encryptedData, opts)
+	entry.cancel()
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.done)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testWaitFor polls until done reports true, failing after ten seconds.
+func testWaitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(10 * time.Second); !done(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// testDecryptCacheWaiters returns how many callers wait on c's entries.
+func testDecryptCacheWaiters(c *decryptCache) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waiters := 0
+	for _, entry := range c.entries {
+		waiters += entry.waiters
+	}
+	return waiters
+}
+
+func testCountRuns(t *testing.T, counter string) int {
+	t.Helper()
+
+	runs, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(runs), "run")
+}
+
+func TestDecryptCache(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	path := testFakeSopsBinary(t, "3.12.0", fmt.Sprintf("echo run >> %q\necho '{\"secret\":\"cached-value\"}'", counter))
+	sops, err := probeSopsBinary(t.Context(), path, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &SopsProviderConfig{Sops: sops, decryptCache: newDecryptCache()}
+
+	opts := SopsDecryptOptions{InputType: "json", AgeIdentityValue: testAgeSecretKey, Backend: config.backendOptions()}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			plaintext, err := config.decrypt(t.Context(), []byte("ciphertext"), opts)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !strings.Contains(string(plaintext), "cached-value") {
+				t.Errorf("unexpected plaintext %q", plaintext)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := testCountRuns(t, counter); got != 1 {
+		t.Fatalf("sops ran %d times for identical decryptions, want 1", got)
+	}
+
+	// Another identity must not be served the cached plaintext.
+	other := opts
+	other.AgeIdentityValue = testAgeSecretKey2
+	if _, err := config.decrypt(t.Context(), []byte("ciphertext"), other); err != nil {
+		t.Fatal(err)
+	}
+	if got := testCountRuns(t, counter); got != 2 {
+		t.Fatalf("sops ran %d times after changing the identity, want 2", got)
+	}
+
+	plaintext, err := config.decrypt(t.Context(), []byte("ciphertext"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wipeDecryptCaches()
+	if strings.Contains(string(plaintext), "cached-value") {
+		t.Fatal("plaintext was not wiped")
+	}
+}
+
+func TestDecryptCacheCancelledCaller(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "runs")
+	release := filepath.Join(dir, "release")
+	path := testFakeSopsBinary(t, "3.12.0", fmt.Sprintf("echo run >> %q\nwhile [ ! -e %q ]; do sleep 0.05; done\necho '{\"secret\":\"cached-value\"}'", counter, release))
+	sops, err := probeSopsBinary(t.Context(), path, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &SopsProviderConfig{Sops: sops, decryptCache: newDecryptCache()}
+	opts := SopsDecryptOptions{InputType: "json", AgeIdentityValue: testAgeSecretKey, Backend: config.backendOptions()}
+
+	firstCtx, cancelFirst := context.WithCancel(t.Context())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := config.decrypt(firstCtx, []byte("ciphertext"), opts)
+		firstErr <- err
+	}()
+	testWaitFor(t, "sops to start", func() bool {
+		_, err := os.Stat(counter)
+		return err == nil
+	})
+
+	type result struct {
+		plaintext []byte
+		err       error
+	}
+	second := make(chan result, 1)
+	go func() {
+		plaintext, err := config.decrypt(t.Context(), []byte("ciphertext"), opts)
+		second <- result{plaintext, err}
+	}()
+
+	testWaitFor(t, "both callers to wait", func() bool {
+		return testDecryptCacheWaiters(config.decryptCache) == 2
+	})
+
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller got %v, want context.Canceled", err)
+	}
+
+	if err := os.WriteFile(release, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := <-second
+	if got.err != nil {
+		t.Fatalf("live caller failed with the cancelled caller's error: %s", got.err)
+	}
+	if !strings.Contains(string(got.plaintext), "cached-value") {
+		t.Errorf("unexpected plaintext %q", got.plaintext)
+	}
+	if runs := testCountRuns(t, counter); runs != 1 {
+		t.Fatalf("sops ran %d times, want 1", runs)
+	}
+}
+
+func TestDecryptCacheAllCallersCancelled(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "runs")
+	pidFile := filepath.Join(dir, "pid")
+	release := filepath.Join(dir, "release")
+	path := testFakeSopsBinary(t, "3.12.0", fmt.Sprintf("echo run >> %q\necho $$ > %q\nwhile [ ! -e %q ]; do sleep 0.05; done\necho '{\"secret\":\"cached-value\"}'", counter, pidFile, release))
+	sops, err := probeSopsBinary(t.Context(), path, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With a single slot, a sops process left running would block the retry.
+	sops.setMaxConcurrent(1)
+	config := &SopsProviderConfig{Sops: sops, decryptCache: newDecryptCache()}
+	opts := SopsDecryptOptions{InputType: "json", AgeIdentityValue: testAgeSecretKey, Backend: config.backendOptions()}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := config.decrypt(ctx, []byte("ciphertext"), opts)
+			errs <- err
+		}()
+	}
+	testWaitFor(t, "sops to start", func() bool {
+		content, err := os.ReadFile(pidFile)
+		return err == nil && strings.HasSuffix(string(content), "\n")
+	})
+	content, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testWaitFor(t, "both callers to wait", func() bool {
+		return testDecryptCacheWaiters(config.decryptCache) == 2
+	})
+
+	cancel()
+	for range 2 {
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("cancelled caller got %v, want context.Canceled", err)
+		}
+	}
+	testWaitFor(t, "sops to be killed", func() bool {
+		return syscall.Kill(pid, 0) != nil
+	})
+
+	// The cancelled decryption is not cached, and its slot is free again.
+	if err := os.WriteFile(release, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := config.decrypt(t.Context(), []byte("ciphertext"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(plaintext), "cached-value") {
+		t.Errorf("unexpected plaintext %q", plaintext)
+	}
+	if runs := testCountRuns(t, counter); runs != 2 {
+		t.Fatalf("sops ran %d times, want 2", runs)
+	}
+}
+
+func TestDecryptCacheRelease(t *testing.T) {
+	path := testFakeSopsBinary(t, "3.12.0", "echo '{\"secret\":\"cached-value\"}'")
+	sops, err := probeSopsBinary(t.Context(), path, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &SopsProviderConfig{Sops: sops, decryptCache: newDecryptCache()}
+
+	plaintext, err := config.decrypt(t.Context(), []byte("ciphertext"), SopsDecryptOptions{InputType: "json", Backend: config.backendOptions()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.decryptCache.release()
+	if strings.Contains(string(plaintext), "cached-value") {
+		t.Fatal("plaintext of the released cache was not wiped")
+	}
+	decryptCachesMu.Lock()
+	defer decryptCachesMu.Unlock()
+	for _, c := range decryptCaches {
+		if c == config.decryptCache {
+			t.Fatal("released cache is still tracked")
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -20,9 +22,13 @@ type DecryptDataSource struct {
 }
 
 type DecryptDataSourceModel struct {
-	Input     types.Dynamic `tfsdk:"input"`
-	InputType types.String  `tfsdk:"input_type"`
-	Output    types.Dynamic `tfsdk:"output"`
+	Input            types.Dynamic `tfsdk:"input"`
+	InputType        types.String  `tfsdk:"input_type"`
+	AgeIdentityPath  types.String  `tfsdk:"age_identity_path"`
+	AgeIdentityValue types.String  `tfsdk:"age_identity_value"`
+	OutputType       types.String  `tfsdk:"output_type"`
+	Output           types.Dynamic `tfsdk:"output"`
+	OutputBase64     types.String  `tfsdk:"output_base64"`
 }
 
 func (d *DecryptDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -40,11 +46,38 @@ func (d *DecryptDataSource) Schema(ctx context.Context, req datasource.SchemaReq
 				Sensitive:           true,
 			},
 			"input_type": schema.StringAttribute{
-				MarkdownDescription: "The format of the encrypted input. Valid values are \"json\" or \"yaml\".",
+				MarkdownDescription: "The format of the encrypted input. Valid values are \"json\", \"yaml\", \"dotenv\", \"ini\" or \"binary\" for documents encrypted from a binary payload, as written by `sops encrypt --input-type binary`.",
 				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(sopsDecryptInputTypes...),
+				},
+			},
+			"age_identity_path": schema.StringAttribute{
+				MarkdownDescription: "Path to an age identity file used instead of the provider's age identities for this decryption only.",
+				Optional:            true,
+			},
+			"age_identity_value": schema.StringAttribute{
+				MarkdownDescription: "Raw age identity value used instead of the provider's age identities for this decryption only. Takes precedence over `age_identity_path`.",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					ageIdentityValidator{},
+				},
+			},
+			"output_type": schema.StringAttribute{
+				MarkdownDescription: "How the decrypted data is returned. `\"json\"` returns the data structure in `output`; `\"binary\"` returns the payload of a document encrypted from a binary input in `output_base64`, and in `output` as a string when it is valid UTF-8. Defaults to `\"binary\"` when `input_type` is `\"binary\"`, otherwise `\"json\"`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(sopsDecryptOutputTypes...),
+				},
 			},
 			"output": schema.DynamicAttribute{
-				MarkdownDescription: "The decrypted data structure.",
+				MarkdownDescription: "The decrypted data structure, or the decrypted text when `output_type` is `\"binary\"`.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"output_base64": schema.StringAttribute{
+				MarkdownDescription: "The base64-encoded decrypted payload when `output_type` is `\"binary\"`. Use it for payloads that are not text, such as keystores.",
 				Computed:            true,
 				Sensitive:           true,
 			},
@@ -86,16 +119,6 @@ func (d *DecryptDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		return
 	}
 
-	var ageIdentityPath, ageIdentityValue string
-	if d.client != nil {
-		if !d.client.AgeIdentityPath.IsNull() {
-			ageIdentityPath = d.client.AgeIdentityPath.ValueString()
-		}
-		if !d.client.AgeIdentityValue.IsNull() {
-			ageIdentityValue = d.client.AgeIdentityValue.ValueString()
-		}
-	}
-
 	if data.InputType.IsNull() || data.InputType.IsUnknown() {
 		resp.Diagnostics.AddError(
 			"Missing Input Type",
@@ -113,24 +136,38 @@ func (d *DecryptDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		return
 	}
 
-	decryptedJSON, err := decryptWithSops(ctx, inputBytes, SopsDecryptOptions{
-		AgeIdentityPath:  ageIdentityPath,
-		AgeIdentityValue: ageIdentityValue,
-		InputType:        inputType,
-	})
+	opts, err := d.client.decryptOptionsFor(ctx, data.AgeIdentityPath, data.AgeIdentityValue)
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"SOPS Decryption Failed",
-			fmt.Sprintf("Failed to decrypt content: %s", err),
+			"Age Identity Unavailable",
+			fmt.Sprintf("Failed to load the age identity: %s", err),
 		)
 		return
 	}
+	opts.InputType = inputType
+	opts.OutputType = data.OutputType.ValueString()
+	if opts.OutputType == "" && inputType == "binary" {
+		opts.OutputType = "binary"
+	}
+
+	decryptedJSON, err := d.client.decrypt(ctx, inputBytes, opts)
+	if err != nil {
+		addSopsErrorDiagnostic(&resp.Diagnostics, "SOPS Decryption Failed", "Failed to decrypt content", err)
+		return
+	}
+
+	if opts.OutputType == "binary" {
+		data.Output, data.OutputBase64 = binaryOutputValues(decryptedJSON)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	data.OutputBase64 = types.StringNull()
 
 	outputValue, err := unmarshalToDynamicValue(decryptedJSON)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"JSON Parsing Failed",
-			fmt.Sprintf("Failed to parse SOPS decrypted output: %s", err),
+			newRedactor(plaintextValues(decryptedJSON)...).redact(fmt.Sprintf("Failed to parse SOPS decrypted output: %s", err)),
 		)
 		return
 	}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestAccDecryptDataSource_IdentityOverride(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "team-b.txt")
+	if err := os.WriteFile(keyFile, []byte(testAgeSecretKey2+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %[1]q
+}
+
+data "sops_encrypt" "team_a" {
+  input = {
+    secret = "team-a-value"
+  }
+  age_recipients = [%[2]q]
+}
+
+data "sops_encrypt" "team_b" {
+  input = {
+    secret = "team-b-value"
+  }
+  age_recipients = [%[3]q]
+}
+
+data "sops_decrypt" "team_a" {
+  input      = data.sops_encrypt.team_a.output
+  input_type = "json"
+}
+
+data "sops_decrypt" "team_b_value" {
+  input              = data.sops_encrypt.team_b.output
+  input_type         = "json"
+  age_identity_value = %[4]q
+}
+
+data "sops_decrypt" "team_b_path" {
+  input             = data.sops_encrypt.team_b.output
+  input_type        = "json"
+  age_identity_path = %[5]q
+}
+`, testAgeSecretKey, testAgePublicKey, testAgePublicKey2, testAgeSecretKey2, keyFile),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.team_a", "output.secret", "team-a-value"),
+					resource.TestCheckResourceAttr("data.sops_decrypt.team_b_value", "output.secret", "team-b-value"),
+					resource.TestCheckResourceAttr("data.sops_decrypt.team_b_path", "output.secret", "team-b-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDecryptDataSource_IdentityOverrideReplacesProviderIdentity(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// The provider identity could decrypt this, but the override
+				// is the only identity used.
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input              = data.sops_encrypt.test.output
+  input_type         = "json"
+  age_identity_value = %q
+}
+`, testAgeSecretKey, testAgePublicKey, testAgeSecretKey2),
+				ExpectError: regexp.MustCompile("SOPS Decryption Failed"),
+			},
+		},
+	})
+}
+
+func TestAccDecryptEphemeralResource_IdentityOverride(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		PreCheck:                 func() { testAccDecryptEphemeralPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithEcho,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_encrypt" "source" {
+  input = {
+    secret = "override-value"
+  }
+  age_recipients = [%q]
+}
+
+ephemeral "sops_decrypt" "test" {
+  input              = data.sops_encrypt.source.output
+  input_type         = "json"
+  age_identity_value = %q
+}
+
+provider "echo" {
+  data = ephemeral.sops_decrypt.test.output
+}
+
+resource "echo" "test" {}
+`, testAgeSecretKey, testAgePublicKey2, testAgeSecretKey2),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"echo.test",
+						tfjsonpath.New("data").AtMapKey("secret"),
+						knownvalue.StringExact("override-value"),
+					),
+				},
+			},
+		},
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
@@ -14,22 +15,38 @@ import (
 )
 
 var _ datasource.DataSource = &EncryptDataSource{}
+var _ datasource.DataSourceWithConfigure = &EncryptDataSource{}
+var _ datasource.DataSourceWithConfigValidators = &EncryptDataSource{}
 
 func NewEncryptDataSource() datasource.DataSource {
 	return &EncryptDataSource{}
 }
 
-type EncryptDataSource struct{}
+type EncryptDataSource struct {
+	client *SopsProviderConfig
+}
 
 type EncryptDataSourceModel struct {
 	Input             types.Dynamic `tfsdk:"input"`
+	InputBase64       types.String  `tfsdk:"input_base64"`
+	InputType         types.String  `tfsdk:"input_type"`
 	Age               types.List    `tfsdk:"age_recipients"`
+	PGP               types.List    `tfsdk:"pgp_fingerprints"`
+	HCVaultTransit    types.List    `tfsdk:"hc_vault_transit_uris"`
+	KMS               types.List    `tfsdk:"kms_arns"`
+	KMSRole           types.String  `tfsdk:"kms_role"`
+	KMSContext        types.Map     `tfsdk:"kms_encryption_context"`
+	GCPKMS            types.List    `tfsdk:"gcp_kms_resource_ids"`
+	AzureKV           types.List    `tfsdk:"azure_kv_urls"`
+	KeyGroups         types.List    `tfsdk:"key_group"`
+	ShamirThreshold   types.Int64   `tfsdk:"shamir_threshold"`
 	OutputType        types.String  `tfsdk:"output_type"`
 	OutputIndent      types.Int64   `tfsdk:"output_indent"`
 	UnencryptedSuffix types.String  `tfsdk:"unencrypted_suffix"`
 	EncryptedSuffix   types.String  `tfsdk:"encrypted_suffix"`
 	UnencryptedRegex  types.String  `tfsdk:"unencrypted_regex"`
 	EncryptedRegex    types.String  `tfsdk:"encrypted_regex"`
+	Filename          types.String  `tfsdk:"filename"`
 	Output            types.String  `tfsdk:"output"`
 }
 
@@ -39,28 +56,114 @@ func (d *EncryptDataSource) Metadata(ctx context.Context, req datasource.Metadat
 
 func (d *EncryptDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Encrypts data using SOPS with Age encryption",
+		MarkdownDescription: "Encrypts data using SOPS with age, PGP or cloud key management encryption",
 		Attributes: map[string]schema.Attribute{
 			"input": schema.DynamicAttribute{
-				MarkdownDescription: "The data structure to encrypt. Must be a map/object with string keys. Will be automatically converted to JSON before encryption.",
-				Required:            true,
+				MarkdownDescription: "The data structure to encrypt. Must be a map/object with string keys, which is automatically converted to JSON before encryption, or a string when `input_type` is `\"binary\"`. Exactly one of `input` and `input_base64` must be set.",
+				Optional:            true,
 				Sensitive:           true,
 				Validators: []validator.Dynamic{
 					dynamicObjectValidator{},
 				},
 			},
+			"input_base64": schema.StringAttribute{
+				MarkdownDescription: "Base64-encoded payload to encrypt in the SOPS binary format, such as `filebase64(\"keystore.p12\")`. Implies `input_type = \"binary\"`.",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					base64Validator{},
+				},
+			},
+			"input_type": schema.StringAttribute{
+				MarkdownDescription: "How the input is encrypted. `\"json\"` encrypts each value of a structured `input`; `\"binary\"` encrypts a text `input` or `input_base64` as a single opaque payload. Defaults to `\"binary\"` when `input_base64` is set, otherwise `\"json\"`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(sopsEncryptInputTypes...),
+				},
+			},
 			"age_recipients": schema.ListAttribute{
 				ElementType:         types.StringType,
-				MarkdownDescription: "List of age recipients to encrypt the data for. Each recipient can decrypt the encrypted output with their corresponding age identity.",
-				Required:            true,
+				MarkdownDescription: "List of age recipients to encrypt the data for, as `age1...` or post-quantum `age1pq1...` public keys, `age1<plugin>1...` plugin recipients or `ssh-ed25519`/`ssh-rsa` public keys. Plugin recipients require the matching `age-plugin-<plugin>` binary on `PATH`. Each recipient can decrypt the encrypted output with their corresponding age identity or SSH private key.",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1), ageRecipientValidator{}),
+				},
+			},
+			"pgp_fingerprints": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "List of PGP key fingerprints to encrypt the data for. The public keys must be present in the GnuPG keyring configured on the provider.",
+				Optional:            true,
 				Validators: []validator.List{
 					listvalidator.SizeAtLeast(1),
 					listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1)),
 				},
 			},
+			"hc_vault_transit_uris": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "List of HashiCorp Vault Transit key URIs to encrypt the data for, such as `https://vault.example.com:8200/v1/transit/keys/my-key`. The provider's Vault address and token are used to reach the server.",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ValueStringsAre(vaultTransitURIValidator),
+				},
+			},
+			"kms_arns": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "List of AWS KMS key ARNs to encrypt the data for.",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ValueStringsAre(kmsARNValidator),
+				},
+			},
+			"kms_role": schema.StringAttribute{
+				MarkdownDescription: "IAM role ARN to assume before using the `kms_arns` keys, including those in `key_group` blocks.",
+				Optional:            true,
+				Validators: []validator.String{
+					iamRoleARNValidator,
+				},
+			},
+			"kms_encryption_context": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "AWS KMS encryption context applied to every `kms_arns` key, including those in `key_group` blocks. The same context is required to decrypt.",
+				Optional:            true,
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(encryptionContextEntryValidator),
+					mapvalidator.ValueStringsAre(encryptionContextEntryValidator),
+				},
+			},
+			"gcp_kms_resource_ids": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "List of GCP KMS crypto key resource IDs to encrypt the data for, in `projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>` format.",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ValueStringsAre(gcpKMSResourceIDValidator),
+				},
+			},
+			"azure_kv_urls": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "List of Azure Key Vault key URLs to encrypt the data for, in `https://<vault>.vault.azure.net/keys/<key>/<version>` format. The version may be omitted to use the latest one.",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ValueStringsAre(azureKVURLValidator),
+				},
+			},
+			"shamir_threshold": schema.Int64Attribute{
+				MarkdownDescription: "Number of `key_group` blocks whose keys are required to decrypt the output. Defaults to all key groups.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
 			"output_type": schema.StringAttribute{
-				MarkdownDescription: "The output format for the encrypted data. Valid values are \"json\" or \"yaml\". Defaults to \"json\".",
+				MarkdownDescription: "The output format for the encrypted data. Valid values are \"json\", \"yaml\", \"dotenv\" or \"ini\". dotenv requires `input` to be a flat map of strings and ini a map of sections holding strings. Defaults to \"json\".",
 				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(sopsFormats...),
+				},
 			},
 			"output_indent": schema.Int64Attribute{
 				MarkdownDescription: "Number of spaces to indent the encrypted output.",
@@ -85,11 +188,105 @@ func (d *EncryptDataSource) Schema(ctx context.Context, req datasource.SchemaReq
 				MarkdownDescription: "Set the encrypted key regex. When specified, only keys matching this regex will be encrypted.",
 				Optional:            true,
 			},
+			"filename": schema.StringAttribute{
+				MarkdownDescription: "Path of the file the output is meant for, relative to the directory of the provider's `config_path` unless absolute. It is matched against the `path_regex` of the creation rules in that file. Keys and encryption settings then come from the matching rule, so top-level recipients become optional; any that are set take precedence over the rule's keys, as with the sops CLI. Cannot be combined with `key_group` blocks.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
 			"output": schema.StringAttribute{
 				MarkdownDescription: "The encrypted data as a raw string (JSON or YAML serialized). Contains the original structure with encrypted values (ENC[...]) and SOPS metadata. Use `jsondecode()` or `yamldecode()` to parse the output string.",
 				Computed:            true,
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"key_group": schema.ListNestedBlock{
+				MarkdownDescription: "Key group for Shamir secret sharing. The data key is split across the key groups, and decrypting requires keys from `shamir_threshold` of them. Any key within a group can unlock that group's share. Cannot be combined with the top-level recipient attributes.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"age_recipients": schema.ListAttribute{
+							ElementType:         types.StringType,
+							MarkdownDescription: "Age recipients in this key group.",
+							Optional:            true,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+								listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1), ageRecipientValidator{}),
+							},
+						},
+						"pgp_fingerprints": schema.ListAttribute{
+							ElementType:         types.StringType,
+							MarkdownDescription: "PGP key fingerprints in this key group.",
+							Optional:            true,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+								listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1)),
+							},
+						},
+						"hc_vault_transit_uris": schema.ListAttribute{
+							ElementType:         types.StringType,
+							MarkdownDescription: "HashiCorp Vault Transit key URIs in this key group.",
+							Optional:            true,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+								listvalidator.ValueStringsAre(vaultTransitURIValidator),
+							},
+						},
+						"kms_arns": schema.ListAttribute{
+							ElementType:         types.StringType,
+							MarkdownDescription: "AWS KMS key ARNs in this key group.",
+							Optional:            true,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+								listvalidator.ValueStringsAre(kmsARNValidator),
+							},
+						},
+						"gcp_kms_resource_ids": schema.ListAttribute{
+							ElementType:         types.StringType,
+							MarkdownDescription: "GCP KMS crypto key resource IDs in this key group.",
+							Optional:            true,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+								listvalidator.ValueStringsAre(gcpKMSResourceIDValidator),
+							},
+						},
+						"azure_kv_urls": schema.ListAttribute{
+							ElementType:         types.StringType,
+							MarkdownDescription: "Azure Key Vault key URLs in this key group.",
+							Optional:            true,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+								listvalidator.ValueStringsAre(azureKVURLValidator),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *EncryptDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	config, ok := req.ProviderData.(*SopsProviderConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *SopsProviderConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = config
+}
+
+func (d *EncryptDataSource) ConfigValidators(ctx context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		encryptRecipientsValidator{},
+		encryptInputValidator{},
 	}
 }
 
@@ -101,22 +298,33 @@ func (d *EncryptDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		return
 	}
 
-	var ageRecipients []string
-	resp.Diagnostics.Append(data.Age.ElementsAs(ctx, &ageRecipients, false)...)
+	recipients, diags := EncryptKeyGroupModel{
+		Age:            data.Age,
+		PGP:            data.PGP,
+		HCVaultTransit: data.HCVaultTransit,
+		KMS:            data.KMS,
+		GCPKMS:         data.GCPKMS,
+		AzureKV:        data.AzureKV,
+	}.expand(ctx)
+	resp.Diagnostics.Append(diags...)
+
+	var keyGroupModels []EncryptKeyGroupModel
+	resp.Diagnostics.Append(data.KeyGroups.ElementsAs(ctx, &keyGroupModels, false)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	inputValue, err := convertDynamicValueToGo(data.Input)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Value Conversion Failed",
-			fmt.Sprintf("Failed to convert input to Go value: %s", err),
-		)
-		return
+	keyGroups, diags := expandKeyGroups(ctx, keyGroupModels)
+	resp.Diagnostics.Append(diags...)
+
+	var kmsEncryptionContext map[string]string
+	if !data.KMSContext.IsNull() {
+		resp.Diagnostics.Append(data.KMSContext.ElementsAs(ctx, &kmsEncryptionContext, false)...)
 	}
 
-	inputMap := inputValue.(map[string]interface{})
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	outputType := "json"
 	if !data.OutputType.IsNull() && !data.OutputType.IsUnknown() {
@@ -126,6 +334,12 @@ func (d *EncryptDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		outputType = "json"
 	}
 
+	var shamirThreshold *int64
+	if !data.ShamirThreshold.IsNull() && !data.ShamirThreshold.IsUnknown() {
+		value := data.ShamirThreshold.ValueInt64()
+		shamirThreshold = &value
+	}
+
 	var outputIndent *int64
 	if !data.OutputIndent.IsNull() && !data.OutputIndent.IsUnknown() {
 		value := data.OutputIndent.ValueInt64()
@@ -156,20 +370,54 @@ func (d *EncryptDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		encryptedRegex = &value
 	}
 
-	encryptedBytes, err := encryptWithSops(ctx, inputMap, SopsEncryptOptions{
-		AgeRecipients:     ageRecipients,
-		OutputType:        outputType,
-		OutputIndent:      outputIndent,
-		UnencryptedSuffix: unencryptedSuffix,
-		EncryptedSuffix:   encryptedSuffix,
-		UnencryptedRegex:  unencryptedRegex,
-		EncryptedRegex:    encryptedRegex,
-	})
+	opts := SopsEncryptOptions{
+		AgeRecipients:        recipients.AgeRecipients,
+		PGPFingerprints:      recipients.PGPFingerprints,
+		HCVaultTransitURIs:   recipients.HCVaultTransitURIs,
+		KMSARNs:              recipients.KMSARNs,
+		KMSRole:              data.KMSRole.ValueString(),
+		KMSEncryptionContext: kmsEncryptionContext,
+		GCPKMSResourceIDs:    recipients.GCPKMSResourceIDs,
+		AzureKVURLs:          recipients.AzureKVURLs,
+		KeyGroups:            keyGroups,
+		ShamirThreshold:      shamirThreshold,
+		OutputType:           outputType,
+		OutputIndent:         outputIndent,
+		UnencryptedSuffix:    unencryptedSuffix,
+		EncryptedSuffix:      encryptedSuffix,
+		UnencryptedRegex:     unencryptedRegex,
+		EncryptedRegex:       encryptedRegex,
+		Filename:             data.Filename.ValueString(),
+		Backend:              d.client.backendOptions(),
+	}
+
+	var encryptedBytes []byte
+	var err error
+	if data.InputType.ValueString() == "binary" || !data.InputBase64.IsNull() {
+		var payload []byte
+		payload, err = binaryInput(data.Input, data.InputBase64)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Value Conversion Failed",
+				fmt.Sprintf("Failed to read binary input: %s", err),
+			)
+			return
+		}
+		encryptedBytes, err = encryptBinaryWithSops(ctx, payload, opts)
+	} else {
+		var inputValue interface{}
+		inputValue, err = convertDynamicValueToGo(data.Input)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Value Conversion Failed",
+				fmt.Sprintf("Failed to convert input to Go value: %s", err),
+			)
+			return
+		}
+		encryptedBytes, err = encryptWithSops(ctx, inputValue.(map[string]interface{}), opts)
+	}
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"SOPS Encryption Failed",
-			fmt.Sprintf("Failed to encrypt content: %s", err),
-		)
+		addSopsErrorDiagnostic(&resp.Diagnostics, "SOPS Encryption Failed", "Failed to encrypt content", err)
 		return
 	}
 
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// EncryptKeyGroupModel is a key_group block of sops_encrypt. The top-level
+// recipient attributes share the same shape.
+type EncryptKeyGroupModel struct {
+	Age            types.List `tfsdk:"age_recipients"`
+	PGP            types.List `tfsdk:"pgp_fingerprints"`
+	HCVaultTransit types.List `tfsdk:"hc_vault_transit_uris"`
+	KMS            types.List `tfsdk:"kms_arns"`
+	GCPKMS         types.List `tfsdk:"gcp_kms_resource_ids"`
+	AzureKV        types.List `tfsdk:"azure_kv_urls"`
+}
+
+func (m EncryptKeyGroupModel) lists() []types.List {
+	return []types.List{m.Age, m.PGP, m.HCVaultTransit, m.KMS, m.GCPKMS, m.AzureKV}
+}
+
+// isEmpty reports whether no recipient list is set. Unknown lists count as
+// set, since they may still resolve to keys.
+func (m EncryptKeyGroupModel) isEmpty() bool {
+	for _, list := range m.lists() {
+		if !list.IsNull() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m EncryptKeyGroupModel) expand(ctx context.Context) (SopsKeyGroup, diag.Diagnostics) {
+	var group SopsKeyGroup
+	var diags diag.Diagnostics
+
+	targets := []*[]string{
+		&group.AgeRecipients,
+		&group.PGPFingerprints,
+		&group.HCVaultTransitURIs,
+		&group.KMSARNs,
+		&group.GCPKMSResourceIDs,
+		&group.AzureKVURLs,
+	}
+	for i, list := range m.lists() {
+		if !list.IsNull() {
+			diags.Append(list.ElementsAs(ctx, targets[i], false)...)
+		}
+	}
+
+	return group, diags
+}
+
+func expandKeyGroups(ctx context.Context, models []EncryptKeyGroupModel) ([]SopsKeyGroup, diag.Diagnostics) {
+	var groups []SopsKeyGroup
+	var diags diag.Diagnostics
+
+	for _, model := range models {
+		group, groupDiags := model.expand(ctx)
+		diags.Append(groupDiags...)
+		groups = append(groups, group)
+	}
+
+	return groups, diags
+}
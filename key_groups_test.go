@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccKeyGroupsConfig(identity string) string {
+	return fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+resource "sops_encrypt" "test" {
+  input = {
+    secret = "shamir-value"
+  }
+
+  key_group {
+    age_recipients = [%q]
+  }
+
+  key_group {
+    age_recipients = [%q]
+  }
+
+  shamir_threshold = 2
+}
+
+data "sops_decrypt" "test" {
+  input      = sops_encrypt.test.output
+  input_type = "json"
+}
+`, identity, testAgePublicKey, testAgePublicKey2)
+}
+
+func TestAccEncryptResource_KeyGroupsShamirThreshold(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKeyGroupsConfig(testAgeSecretKey + "\n" + testAgeSecretKey2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("sops_encrypt.test", "output", regexp.MustCompile(`"shamir_threshold":\s*2`)),
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "shamir-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptResource_KeyGroupsThresholdNotMet(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccKeyGroupsConfig(testAgeSecretKey),
+				ExpectError: regexp.MustCompile("SOPS Decryption Failed"),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_KeyGroupValidation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body string
+		want string
+	}{
+		"combined_with_top_level": {
+			body: fmt.Sprintf(`
+  age_recipients = [%[1]q]
+  key_group {
+    age_recipients = [%[1]q]
+  }
+`, testAgePublicKey),
+			want: "cannot be combined",
+		},
+		"empty_group": {
+			body: `
+  key_group {}
+`,
+			want: "Empty Key Group",
+		},
+		"threshold_exceeds_groups": {
+			body: fmt.Sprintf(`
+  key_group {
+    age_recipients = [%q]
+  }
+  shamir_threshold = 2
+`, testAgePublicKey),
+			want: "cannot exceed the number of key_group",
+		},
+		"threshold_without_groups": {
+			body: fmt.Sprintf(`
+  age_recipients   = [%q]
+  shamir_threshold = 1
+`, testAgePublicKey),
+			want: "requires at least one key_group",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				PreCheck:                 func() { testAccPreCheck(t) },
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+%s}
+`, tc.body),
+						ExpectError: regexp.MustCompile(tc.want),
+					},
+				},
+			})
+		})
+	}
+}
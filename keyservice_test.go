@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testKeyService starts a local sops keyservice on a unix socket that holds
+// testAgeSecretKey, and returns its address.
+func testKeyService(t *testing.T) string {
+	t.Helper()
+
+	sops, err := exec.LookPath(sopsBinary)
+	if err != nil {
+		t.Skip("sops is not installed")
+	}
+
+	// Unix socket paths are length-limited, so avoid the long paths of
+	// t.TempDir.
+	dir, err := os.MkdirTemp("", "keyservice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "sops.sock")
+
+	server := exec.Command(sops, "keyservice", "--network", "unix", "--address", socket)
+	server.Env = append(os.Environ(), "SOPS_AGE_KEY="+testAgeSecretKey)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start sops keyservice: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Process.Kill()
+		_ = server.Wait()
+	})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("sops keyservice did not become ready")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return "unix://" + socket
+}
+
+func TestAccEncryptDecrypt_KeyService(t *testing.T) {
+	address := testKeyService(t)
+
+	// Only the keyservice holds the identity.
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  keyservice_addresses    = [%q]
+  enable_local_keyservice = false
+}
+
+resource "sops_encrypt" "test" {
+  input = {
+    secret = "keyservice-value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = sops_encrypt.test.output
+  input_type = "json"
+}
+`, address, testAgePublicKey),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "keyservice-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccProvider_InvalidKeyServiceAddress(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  keyservice_addresses = ["localhost:5000"]
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+`, testAgePublicKey),
+				ExpectError: regexp.MustCompile("must be a keyservice address"),
+			},
+		},
+	})
+}
+
+func TestAccProvider_LocalKeyServiceDisabledWithoutAddresses(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  enable_local_keyservice = false
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+`, testAgePublicKey),
+				ExpectError: regexp.MustCompile("Missing Keyservice Address"),
+			},
+		},
+	})
+}
@@ -25,6 +25,7 @@ func main() {
 	}
 
 	err := providerserver.Serve(context.Background(), New(version), opts)
+	wipeDecryptCaches()
 	if err != nil {
 		log.Fatal(err.Error())
 	}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testPGPKeyring creates a throwaway GnuPG home holding a single
+// passphrase-less key and returns the directory and the key fingerprint.
+func testPGPKeyring(t *testing.T) (string, string) {
+	t.Helper()
+
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	// gpg-agent sockets live in the home directory and their paths are
+	// length-limited, so avoid the long paths of t.TempDir.
+	gnupgHome, err := os.MkdirTemp("", "gnupg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--homedir", gnupgHome, "--kill", "gpg-agent").Run()
+		_ = os.RemoveAll(gnupgHome)
+	})
+
+	gen := exec.Command(gpg, "--homedir", gnupgHome, "--batch", "--pinentry-mode", "loopback", "--passphrase", "",
+		"--quick-generate-key", "sops-test@example.com", "rsa2048", "encrypt", "never")
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate PGP key: %s: %s", err, out)
+	}
+
+	out, err := exec.Command(gpg, "--homedir", gnupgHome, "--batch", "--with-colons", "--list-secret-keys").Output()
+	if err != nil {
+		t.Fatalf("failed to list PGP keys: %s", err)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) > 9 && fields[0] == "fpr" {
+			return gnupgHome, fields[9]
+		}
+	}
+
+	t.Fatalf("no fingerprint found in gpg output: %s", out)
+	return "", ""
+}
+
+func TestAccEncryptDecrypt_PGP(t *testing.T) {
+	gnupgHome, fingerprint := testPGPKeyring(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  gnupg_home = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "pgp-value"
+  }
+  pgp_fingerprints = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, gnupgHome, fingerprint),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "pgp-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptResource_PGPAndAge(t *testing.T) {
+	gnupgHome, fingerprint := testPGPKeyring(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// Either recipient type can decrypt; the age identity is
+				// deliberately not configured so only PGP can succeed.
+				Config: fmt.Sprintf(`
+provider "sops" {
+  gnupg_home = %q
+}
+
+resource "sops_encrypt" "test" {
+  input = {
+    secret = "mixed-value"
+  }
+  age_recipients   = [%q]
+  pgp_fingerprints = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = sops_encrypt.test.output
+  input_type = "json"
+}
+`, gnupgHome, testAgePublicKey, fingerprint),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "mixed-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_NoRecipients(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile("Invalid Attribute Combination"),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_GnuPGHomeMissing(t *testing.T) {
+	encrypted := encryptFixture(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+  gnupg_home         = "~/definitely-not-here/.gnupg"
+}
+
+data "sops_decrypt" "test" {
+  input      = %q
+  input_type = "json"
+}
+`, testAgeSecretKey, encrypted),
+				ExpectError: regexp.MustCompile("GnuPG home directory not found"),
+			},
+		},
+	})
+}
@@ -2,13 +2,21 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -17,11 +25,41 @@ var _ provider.ProviderWithEphemeralResources = &SopsProvider{}
 
 type SopsProvider struct {
 	version string
+
+	// decryptCache is released when a later Configure replaces it.
+	decryptCache *decryptCache
 }
 
 type SopsProviderModel struct {
-	AgeIdentityPath  types.String `tfsdk:"age_identity_path"`
-	AgeIdentityValue types.String `tfsdk:"age_identity_value"`
+	AgeIdentityPath         types.String `tfsdk:"age_identity_path"`
+	AgeIdentityValue        types.String `tfsdk:"age_identity_value"`
+	AgeSSHKeyPath           types.String `tfsdk:"age_ssh_private_key_path"`
+	AgeIdentityPassphrase   types.String `tfsdk:"age_identity_passphrase"`
+	AgeIdentityPaths        types.List   `tfsdk:"age_identity_paths"`
+	AgeIdentityValues       types.List   `tfsdk:"age_identity_values"`
+	AgeIdentityCommand      types.List   `tfsdk:"age_identity_command"`
+	GnuPGHome               types.String `tfsdk:"gnupg_home"`
+	VaultAddress            types.String `tfsdk:"vault_address"`
+	VaultToken              types.String `tfsdk:"vault_token"`
+	AWSRegion               types.String `tfsdk:"aws_region"`
+	AWSProfile              types.String `tfsdk:"aws_profile"`
+	AWSKMSEndpoint          types.String `tfsdk:"aws_kms_endpoint"`
+	GCPCredentials          types.String `tfsdk:"gcp_credentials"`
+	GCPAccessToken          types.String `tfsdk:"gcp_access_token"`
+	GCPKMSEndpoint          types.String `tfsdk:"gcp_kms_endpoint"`
+	AzureTenantID           types.String `tfsdk:"azure_tenant_id"`
+	AzureClientID           types.String `tfsdk:"azure_client_id"`
+	AzureClientSecret       types.String `tfsdk:"azure_client_secret"`
+	AzureAuthorityHost      types.String `tfsdk:"azure_authority_host"`
+	KeyServiceAddresses     types.List   `tfsdk:"keyservice_addresses"`
+	EnableLocalKeyService   types.Bool   `tfsdk:"enable_local_keyservice"`
+	SopsBinaryPath          types.String `tfsdk:"sops_binary_path"`
+	SopsBinarySHA256        types.String `tfsdk:"sops_binary_sha256"`
+	OperationTimeout        types.String `tfsdk:"operation_timeout"`
+	MaxConcurrentOperations types.Int64  `tfsdk:"max_concurrent_operations"`
+	ConfigPath              types.String `tfsdk:"config_path"`
+	InheritEnvironment      types.Bool   `tfsdk:"inherit_environment"`
+	Environment             types.Map    `tfsdk:"environment"`
 }
 
 func (p *SopsProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -37,10 +75,149 @@ func (p *SopsProvider) Schema(ctx context.Context, req provider.SchemaRequest, r
 				Optional:            true,
 			},
 			"age_identity_value": schema.StringAttribute{
-				MarkdownDescription: "Raw age identity value for SOPS decryption. If both path and value are provided, value takes precedence.",
+				MarkdownDescription: "Raw age identity value for SOPS decryption, either an `AGE-SECRET-KEY-1...` key or an `AGE-PLUGIN-...` plugin identity. Plugin identities require the matching `age-plugin-<plugin>` binary on `PATH`. If both path and value are provided, value takes precedence.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"age_ssh_private_key_path": schema.StringAttribute{
+				MarkdownDescription: "Path to an unencrypted `ssh-ed25519` or `ssh-rsa` private key used as an additional age identity for SOPS decryption.",
+				Optional:            true,
+			},
+			"age_identity_paths": schema.ListAttribute{
+				MarkdownDescription: "Paths to additional age identity files for SOPS decryption. Each file may hold several identities. They are used together with `age_identity_path` or `age_identity_value` and `age_identity_values`, so data encrypted to either an old or a new key can be decrypted while rotating keys.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"age_identity_values": schema.ListAttribute{
+				MarkdownDescription: "Additional raw age identity values for SOPS decryption, used together with the other identity attributes.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"age_identity_command": schema.ListAttribute{
+				MarkdownDescription: "Command, as a program followed by its arguments, that prints age identities to stdout, for example a password manager CLI. It runs without a shell the first time a value is decrypted, is killed if it runs longer than `operation_timeout`, and its output is kept in memory for the rest of the run. The identities are used together with the other identity attributes.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1)),
+				},
+			},
+			"age_identity_passphrase": schema.StringAttribute{
+				MarkdownDescription: "Passphrase of `age_identity_path` or `age_identity_paths` files encrypted with `age -p`. The file is decrypted in memory and the identity handed to SOPS without being written to disk.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"gnupg_home": schema.StringAttribute{
+				MarkdownDescription: "Path to the GnuPG home directory holding the keyring used for PGP encryption and decryption. Defaults to the `GNUPGHOME` environment variable or `~/.gnupg`.",
+				Optional:            true,
+			},
+			"vault_address": schema.StringAttribute{
+				MarkdownDescription: "Address of the HashiCorp Vault server used for Vault Transit encryption and decryption. Defaults to the `VAULT_ADDR` environment variable.",
+				Optional:            true,
+			},
+			"vault_token": schema.StringAttribute{
+				MarkdownDescription: "Token used to authenticate to HashiCorp Vault. Defaults to the `VAULT_TOKEN` environment variable or the Vault token helper.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"aws_region": schema.StringAttribute{
+				MarkdownDescription: "AWS region used for AWS KMS requests that do not carry a region in the key ARN, such as assuming a role. Defaults to the `AWS_REGION` environment variable.",
+				Optional:            true,
+			},
+			"aws_profile": schema.StringAttribute{
+				MarkdownDescription: "Named AWS profile used for AWS KMS credentials. Defaults to the `AWS_PROFILE` environment variable.",
+				Optional:            true,
+			},
+			"aws_kms_endpoint": schema.StringAttribute{
+				MarkdownDescription: "Custom AWS KMS endpoint URL, for example a local KMS emulator or a VPC endpoint.",
+				Optional:            true,
+			},
+			"gcp_credentials": schema.StringAttribute{
+				MarkdownDescription: "Google Cloud service account credentials for GCP KMS, either as a path to a JSON key file or as the JSON content itself. Defaults to Application Default Credentials.",
 				Optional:            true,
 				Sensitive:           true,
 			},
+			"gcp_access_token": schema.StringAttribute{
+				MarkdownDescription: "OAuth 2.0 access token for GCP KMS. Takes precedence over `gcp_credentials`.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"gcp_kms_endpoint": schema.StringAttribute{
+				MarkdownDescription: "Custom GCP KMS endpoint, for example a local KMS emulator or a sovereign cloud, as `host:port`. Requires SOPS 3.13.0 or later.",
+				Optional:            true,
+			},
+			"azure_tenant_id": schema.StringAttribute{
+				MarkdownDescription: "Microsoft Entra tenant ID of the service principal used for Azure Key Vault. Defaults to the `AZURE_TENANT_ID` environment variable.",
+				Optional:            true,
+			},
+			"azure_client_id": schema.StringAttribute{
+				MarkdownDescription: "Client ID of the service principal used for Azure Key Vault. Defaults to the `AZURE_CLIENT_ID` environment variable.",
+				Optional:            true,
+			},
+			"azure_client_secret": schema.StringAttribute{
+				MarkdownDescription: "Client secret of the service principal used for Azure Key Vault. Defaults to the `AZURE_CLIENT_SECRET` environment variable.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"azure_authority_host": schema.StringAttribute{
+				MarkdownDescription: "Microsoft Entra authority host used to obtain Azure Key Vault tokens, for example a sovereign cloud or a local identity fake. The Key Vault endpoint itself is taken from each key URL.",
+				Optional:            true,
+			},
+			"keyservice_addresses": schema.ListAttribute{
+				MarkdownDescription: "Addresses of `sops keyservice` servers that wrap and unwrap data keys, as `tcp://host:port` or `unix:///path/to/socket`. Master keys and cloud credentials then only need to be available to the keyservice.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(keyServiceAddressValidator),
+				},
+			},
+			"enable_local_keyservice": schema.BoolAttribute{
+				MarkdownDescription: "Whether SOPS also uses its built-in keyservice, which needs the master keys locally. Set to `false` together with `keyservice_addresses` to delegate all key operations. Defaults to `true`.",
+				Optional:            true,
+			},
+			"config_path": schema.StringAttribute{
+				MarkdownDescription: "Path to a `.sops.yaml` file. `sops_encrypt` resources and data sources with a `filename` take their keys and encryption settings from the creation rule matching that filename, as `sops --encrypt` does for developers. The file is also used for decryption. By default no SOPS config file is used.",
+				Optional:            true,
+			},
+			"inherit_environment": schema.BoolAttribute{
+				MarkdownDescription: "Whether sops inherits the environment of the Terraform process, including any ambient `SOPS_*`, `AWS_*` or `GNUPGHOME` variables. Set to `false` to start sops from `environment` alone, keeping only `PATH` and `TMPDIR`, so results do not depend on who runs Terraform. Defaults to `true`.",
+				Optional:            true,
+			},
+			"environment": schema.MapAttribute{
+				MarkdownDescription: "Environment variables set for every sops process, overriding inherited ones. Provider settings such as `age_identity_value` or `vault_address` take precedence over these, and the version check runs with the same environment. The variable names, but not their values, are logged. Values of variables whose names contain `KEY`, `SECRET`, `TOKEN`, `PASSWORD`, `PASSPHRASE` or `CREDENTIAL` are also redacted from diagnostics.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(environmentVariableNameValidator),
+				},
+			},
+			"sops_binary_path": schema.StringAttribute{
+				MarkdownDescription: "Path to the `sops` executable, or a name looked up on `PATH`. Defaults to `sops`. The version is checked when the provider is configured, and SOPS 3.9.0 or later is required.",
+				Optional:            true,
+			},
+			"sops_binary_sha256": schema.StringAttribute{
+				MarkdownDescription: "Hex-encoded SHA-256 digest the sops binary must match. The provider refuses to run a binary with any other digest, including one replaced after the provider was configured. Symlinks are resolved before hashing.",
+				Optional:            true,
+				Validators: []validator.String{
+					sha256DigestValidator,
+				},
+			},
+			"operation_timeout": schema.StringAttribute{
+				MarkdownDescription: "Maximum duration of a single sops encryption or decryption, such as `90s` or `10m`. A sops process still running after that is killed. Defaults to `5m`.",
+				Optional:            true,
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+			"max_concurrent_operations": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of sops processes the provider runs at once. Further operations wait for a free slot, which does not count towards `operation_timeout`. Defaults to no limit.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
 		},
 	}
 }
@@ -75,14 +252,258 @@ func (p *SopsProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 		)
 	}
 
+	if data.AgeSSHKeyPath.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("age_ssh_private_key_path"),
+			"Unknown Configuration Value",
+			"The provider cannot decrypt with an SSH private key path that is not yet known. "+
+				"Apply the resource the path depends on first, or supply a known value.",
+		)
+	}
+
+	identityLists := []struct {
+		name  string
+		value types.List
+	}{
+		{"age_identity_paths", data.AgeIdentityPaths},
+		{"age_identity_values", data.AgeIdentityValues},
+		{"age_identity_command", data.AgeIdentityCommand},
+	}
+	for _, list := range identityLists {
+		unknown := list.value.IsUnknown()
+		for _, element := range list.value.Elements() {
+			unknown = unknown || element.IsUnknown()
+		}
+		if unknown {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(list.name),
+				"Unknown Configuration Value",
+				fmt.Sprintf("The provider cannot decrypt with %q entries that are not yet known. "+
+					"Apply the resource the entries depend on first, or supply known values.", list.name),
+			)
+		}
+	}
+
+	// The passphrase unlocks identity files. A known identity value replaces
+	// age_identity_path, but files in age_identity_paths are still read.
+	hasIdentityPaths := data.AgeIdentityPaths.IsUnknown() || len(data.AgeIdentityPaths.Elements()) > 0
+	if data.AgeIdentityPassphrase.IsUnknown() && (!hasKnownIdentityValue || hasIdentityPaths) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("age_identity_passphrase"),
+			"Unknown Configuration Value",
+			"The provider cannot unlock the age identity file with a passphrase that is not yet known. "+
+				"Apply the resource the passphrase depends on first, or supply a known value.",
+		)
+	}
+
+	// Backend settings have no fallback, so an unknown value blocks every
+	// operation that might need it.
+	backendSettings := []struct {
+		name  string
+		value types.String
+	}{
+		{"gnupg_home", data.GnuPGHome},
+		{"vault_address", data.VaultAddress},
+		{"vault_token", data.VaultToken},
+		{"aws_region", data.AWSRegion},
+		{"aws_profile", data.AWSProfile},
+		{"aws_kms_endpoint", data.AWSKMSEndpoint},
+		{"gcp_credentials", data.GCPCredentials},
+		{"gcp_access_token", data.GCPAccessToken},
+		{"gcp_kms_endpoint", data.GCPKMSEndpoint},
+		{"azure_tenant_id", data.AzureTenantID},
+		{"azure_client_id", data.AzureClientID},
+		{"azure_client_secret", data.AzureClientSecret},
+		{"azure_authority_host", data.AzureAuthorityHost},
+		{"config_path", data.ConfigPath},
+	}
+	for _, setting := range backendSettings {
+		if setting.value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(setting.name),
+				"Unknown Configuration Value",
+				fmt.Sprintf("The provider cannot use a %q value that is not yet known. "+
+					"Apply the resource the value depends on first, or supply a known value.", setting.name),
+			)
+		}
+	}
+
+	keyServiceUnknown := data.KeyServiceAddresses.IsUnknown()
+	for _, element := range data.KeyServiceAddresses.Elements() {
+		keyServiceUnknown = keyServiceUnknown || element.IsUnknown()
+	}
+	if keyServiceUnknown {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("keyservice_addresses"),
+			"Unknown Configuration Value",
+			"The provider cannot use keyservice addresses that are not yet known. "+
+				"Apply the resource the addresses depend on first, or supply known values.",
+		)
+	}
+
+	if data.EnableLocalKeyService.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("enable_local_keyservice"),
+			"Unknown Configuration Value",
+			"The provider cannot use an \"enable_local_keyservice\" value that is not yet known. "+
+				"Apply the resource the value depends on first, or supply a known value.",
+		)
+	}
+
+	// Without any keyservice SOPS could neither encrypt nor decrypt.
+	if !data.EnableLocalKeyService.IsNull() && !data.EnableLocalKeyService.IsUnknown() && !data.EnableLocalKeyService.ValueBool() &&
+		!keyServiceUnknown && len(data.KeyServiceAddresses.Elements()) == 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("enable_local_keyservice"),
+			"Missing Keyservice Address",
+			"Disabling the local keyservice requires at least one entry in \"keyservice_addresses\".",
+		)
+	}
+
+	if data.SopsBinaryPath.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("sops_binary_path"),
+			"Unknown Configuration Value",
+			"The provider cannot run a sops binary whose path is not yet known. "+
+				"Apply the resource the path depends on first, or supply a known value.",
+		)
+	}
+
+	if data.SopsBinarySHA256.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("sops_binary_sha256"),
+			"Unknown Configuration Value",
+			"The provider cannot verify the sops binary against a digest that is not yet known. "+
+				"Apply the resource the digest depends on first, or supply a known value.",
+		)
+	}
+
+	if data.InheritEnvironment.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("inherit_environment"),
+			"Unknown Configuration Value",
+			"The provider cannot use an \"inherit_environment\" value that is not yet known. "+
+				"Apply the resource the value depends on first, or supply a known value.",
+		)
+	}
+
+	environmentUnknown := data.Environment.IsUnknown()
+	for _, element := range data.Environment.Elements() {
+		environmentUnknown = environmentUnknown || element.IsUnknown()
+	}
+	if environmentUnknown {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("environment"),
+			"Unknown Configuration Value",
+			"The provider cannot use environment variables that are not yet known. "+
+				"Apply the resource the variables depend on first, or supply known values.",
+		)
+	}
+
+	if data.OperationTimeout.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("operation_timeout"),
+			"Unknown Configuration Value",
+			"The provider cannot use an \"operation_timeout\" value that is not yet known. "+
+				"Apply the resource the value depends on first, or supply a known value.",
+		)
+	}
+
+	if data.MaxConcurrentOperations.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_concurrent_operations"),
+			"Unknown Configuration Value",
+			"The provider cannot use a \"max_concurrent_operations\" value that is not yet known. "+
+				"Apply the resource the value depends on first, or supply a known value.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var environment map[string]string
+	if !data.Environment.IsNull() {
+		resp.Diagnostics.Append(data.Environment.ElementsAs(ctx, &environment, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// The version probe runs in the same environment as every operation.
+	probeEnv := SopsBackendOptions{
+		IsolateEnvironment: !data.InheritEnvironment.IsNull() && !data.InheritEnvironment.ValueBool(),
+		Environment:        environment,
+	}.baseEnviron()
+	sops, err := resolveSopsBinary(ctx, data.SopsBinaryPath, data.SopsBinarySHA256, probeEnv)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("sops_binary_path"),
+			"Unusable SOPS Binary",
+			err.Error(),
+		)
+		return
+	}
+
+	// The schema validator has already checked the duration.
+	sops.Timeout = defaultSopsOperationTimeout
+	if !data.OperationTimeout.IsNull() {
+		sops.Timeout, _ = time.ParseDuration(data.OperationTimeout.ValueString())
+	}
+	if !data.MaxConcurrentOperations.IsNull() {
+		sops.setMaxConcurrent(int(data.MaxConcurrentOperations.ValueInt64()))
+	}
+
+	var ageIdentityPaths, ageIdentityValues, ageIdentityCommand, keyServiceAddresses []string
+	if !data.AgeIdentityPaths.IsNull() {
+		resp.Diagnostics.Append(data.AgeIdentityPaths.ElementsAs(ctx, &ageIdentityPaths, false)...)
+	}
+	if !data.AgeIdentityValues.IsNull() {
+		resp.Diagnostics.Append(data.AgeIdentityValues.ElementsAs(ctx, &ageIdentityValues, false)...)
+	}
+	if !data.AgeIdentityCommand.IsNull() {
+		resp.Diagnostics.Append(data.AgeIdentityCommand.ElementsAs(ctx, &ageIdentityCommand, false)...)
+	}
+	if !data.KeyServiceAddresses.IsNull() {
+		resp.Diagnostics.Append(data.KeyServiceAddresses.ElementsAs(ctx, &keyServiceAddresses, false)...)
+	}
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	config := &SopsProviderConfig{
-		AgeIdentityPath:  data.AgeIdentityPath,
-		AgeIdentityValue: data.AgeIdentityValue,
+		AgeIdentityPath:       data.AgeIdentityPath,
+		AgeIdentityValue:      data.AgeIdentityValue,
+		AgeSSHKeyPath:         data.AgeSSHKeyPath,
+		AgeIdentityPassphrase: data.AgeIdentityPassphrase,
+		AgeIdentityPaths:      ageIdentityPaths,
+		AgeIdentityValues:     ageIdentityValues,
+		AgeIdentityCommand:    ageIdentityCommand,
+		GnuPGHome:             data.GnuPGHome,
+		VaultAddress:          data.VaultAddress,
+		VaultToken:            data.VaultToken,
+		AWSRegion:             data.AWSRegion,
+		AWSProfile:            data.AWSProfile,
+		AWSKMSEndpoint:        data.AWSKMSEndpoint,
+		GCPCredentials:        data.GCPCredentials,
+		GCPAccessToken:        data.GCPAccessToken,
+		GCPKMSEndpoint:        data.GCPKMSEndpoint,
+		AzureTenantID:         data.AzureTenantID,
+		AzureClientID:         data.AzureClientID,
+		AzureClientSecret:     data.AzureClientSecret,
+		AzureAuthorityHost:    data.AzureAuthorityHost,
+		KeyServiceAddresses:   keyServiceAddresses,
+		EnableLocalKeyService: data.EnableLocalKeyService,
+		ConfigPath:            data.ConfigPath,
+		InheritEnvironment:    data.InheritEnvironment,
+		Environment:           environment,
+		Sops:                  sops,
+		decryptCache:          newDecryptCache(),
+	}
+	if p.decryptCache != nil {
+		p.decryptCache.release()
 	}
+	p.decryptCache = config.decryptCache
 
 	resp.DataSourceData = config
 	resp.ResourceData = config
@@ -113,8 +534,149 @@ func (p *SopsProvider) DataSources(ctx context.Context) []func() datasource.Data
 }
 
 type SopsProviderConfig struct {
-	AgeIdentityPath  types.String
-	AgeIdentityValue types.String
+	AgeIdentityPath       types.String
+	AgeIdentityValue      types.String
+	AgeSSHKeyPath         types.String
+	AgeIdentityPassphrase types.String
+	AgeIdentityPaths      []string
+	AgeIdentityValues     []string
+	AgeIdentityCommand    []string
+	GnuPGHome             types.String
+	VaultAddress          types.String
+	VaultToken            types.String
+	AWSRegion             types.String
+	AWSProfile            types.String
+	AWSKMSEndpoint        types.String
+	GCPCredentials        types.String
+	GCPAccessToken        types.String
+	GCPKMSEndpoint        types.String
+	AzureTenantID         types.String
+	AzureClientID         types.String
+	AzureClientSecret     types.String
+	AzureAuthorityHost    types.String
+	KeyServiceAddresses   []string
+	EnableLocalKeyService types.Bool
+	ConfigPath            types.String
+	InheritEnvironment    types.Bool
+	Environment           map[string]string
+	Sops                  *SopsBinary
+
+	ageIdentityCommandMu         sync.Mutex
+	ageIdentityCommandIdentities string
+
+	decryptCache *decryptCache
+}
+
+func (c *SopsProviderConfig) backendOptions() SopsBackendOptions {
+	if c == nil {
+		return SopsBackendOptions{}
+	}
+
+	return SopsBackendOptions{
+		Sops:       c.Sops,
+		ConfigPath: c.ConfigPath.ValueString(),
+
+		GnuPGHome:          c.GnuPGHome.ValueString(),
+		VaultAddress:       c.VaultAddress.ValueString(),
+		VaultToken:         c.VaultToken.ValueString(),
+		AWSRegion:          c.AWSRegion.ValueString(),
+		AWSProfile:         c.AWSProfile.ValueString(),
+		AWSKMSEndpoint:     c.AWSKMSEndpoint.ValueString(),
+		GCPCredentials:     c.GCPCredentials.ValueString(),
+		GCPAccessToken:     c.GCPAccessToken.ValueString(),
+		GCPKMSEndpoint:     c.GCPKMSEndpoint.ValueString(),
+		AzureTenantID:      c.AzureTenantID.ValueString(),
+		AzureClientID:      c.AzureClientID.ValueString(),
+		AzureClientSecret:  c.AzureClientSecret.ValueString(),
+		AzureAuthorityHost: c.AzureAuthorityHost.ValueString(),
+
+		KeyServiceAddresses:    c.KeyServiceAddresses,
+		DisableLocalKeyService: !c.EnableLocalKeyService.IsNull() && !c.EnableLocalKeyService.ValueBool(),
+
+		IsolateEnvironment: !c.InheritEnvironment.IsNull() && !c.InheritEnvironment.ValueBool(),
+		Environment:        c.Environment,
+	}
+}
+
+// decryptOptions returns the provider-level identities and backend settings
+// used for every decryption, running age_identity_command if needed.
+func (c *SopsProviderConfig) decryptOptions(ctx context.Context) (SopsDecryptOptions, error) {
+	if c == nil {
+		return SopsDecryptOptions{}, nil
+	}
+
+	ageIdentityValues := c.AgeIdentityValues
+	commandIdentities, err := c.ageIdentityCommandOutput(ctx)
+	if err != nil {
+		return SopsDecryptOptions{}, err
+	}
+	if commandIdentities != "" {
+		ageIdentityValues = append(append([]string{}, ageIdentityValues...), commandIdentities)
+	}
+
+	return SopsDecryptOptions{
+		AgeIdentityPath:       c.AgeIdentityPath.ValueString(),
+		AgeIdentityValue:      c.AgeIdentityValue.ValueString(),
+		AgeSSHKeyPath:         c.AgeSSHKeyPath.ValueString(),
+		AgeIdentityPassphrase: c.AgeIdentityPassphrase.ValueString(),
+		AgeIdentityPaths:      c.AgeIdentityPaths,
+		AgeIdentityValues:     ageIdentityValues,
+		Backend:               c.backendOptions(),
+	}, nil
+}
+
+// decryptOptionsFor returns decryptOptions, except that an identity set on a
+// single data source or ephemeral resource replaces all of the provider's age
+// identities. The SSH key, passphrase and backend settings still apply.
+func (c *SopsProviderConfig) decryptOptionsFor(ctx context.Context, identityPath, identityValue types.String) (SopsDecryptOptions, error) {
+	if identityPath.IsNull() && identityValue.IsNull() {
+		return c.decryptOptions(ctx)
+	}
+
+	opts := SopsDecryptOptions{
+		AgeIdentityPath:  identityPath.ValueString(),
+		AgeIdentityValue: identityValue.ValueString(),
+	}
+	if c != nil {
+		opts.AgeSSHKeyPath = c.AgeSSHKeyPath.ValueString()
+		opts.AgeIdentityPassphrase = c.AgeIdentityPassphrase.ValueString()
+		opts.Backend = c.backendOptions()
+	}
+	return opts, nil
+}
+
+// decrypt decrypts with sops, reusing earlier results of this provider
+// instance for the same input and options.
+func (c *SopsProviderConfig) decrypt(ctx context.Context, encryptedData []byte, opts SopsDecryptOptions) ([]byte, error) {
+	var cache *decryptCache
+	if c != nil {
+		cache = c.decryptCache
+	}
+	return cache.decrypt(ctx, encryptedData, opts)
+}
+
+// resolveSopsBinary probes the configured sops binary. Unless a path or
+// digest is set, a missing or outdated sops is only reported once an
+// operation needs it, so configurations that just manage age keys work
+// without sops.
+func resolveSopsBinary(ctx context.Context, binaryPath, binarySHA256 types.String, env []string) (*SopsBinary, error) {
+	if binaryPath.IsNull() && binarySHA256.IsNull() {
+		sops, err := probeSopsBinary(ctx, sopsBinary, "", env)
+		if err != nil {
+			return &SopsBinary{Path: sopsBinary, err: err}, nil
+		}
+		return sops, nil
+	}
+
+	name := sopsBinary
+	if !binaryPath.IsNull() {
+		var err error
+		name, err = expandTilde(binaryPath.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve sops binary path %q: %w", binaryPath.ValueString(), err)
+		}
+	}
+	return probeSopsBinary(ctx, name, binarySHA256.ValueString(), env)
 }
 
 func New(version string) func() provider.Provider {
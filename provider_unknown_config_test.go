@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"testing"
 
@@ -82,3 +83,40 @@ data "sops_decrypt" "test" {
 		},
 	})
 }
+
+func TestAccDecrypt_UnknownPassphraseWithIdentityPaths(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// The known identity value does not replace the identity
+				// files, which may need the unknown passphrase.
+				Config: fmt.Sprintf(`
+resource "sops_age_private_key" "k" {}
+
+provider "sops" {
+  alias                   = "keyed"
+  age_identity_value      = %q
+  age_identity_paths      = [%q]
+  age_identity_passphrase = sops_age_private_key.k.public_key
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "passphrase-unknown"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  provider   = sops.keyed
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, testAgeSecretKey, filepath.Join(t.TempDir(), "keys.txt"), testAgePublicKey),
+				ExpectError: regexp.MustCompile(`(?s)Unknown Configuration Value.*unlock\s+the\s+age\s+identity\s+file`),
+			},
+		},
+	})
+}
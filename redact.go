@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const redactedText = "[REDACTED]"
+
+// minRedactedValueLength is the shortest known value that is redacted
+// wherever it appears. Shorter values, such as a PIN, would also blank out
+// ordinary words and exit statuses, so they are only redacted where they
+// stand as a whole value: quoted, or after ":" or "=".
+const minRedactedValueLength = 4
+
+// secretPatterns match secrets wherever they appear, whether or not the
+// provider knows their value.
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Native, post-quantum and plugin age identities.
+	{regexp.MustCompile(`(?i)AGE-SECRET-KEY-(?:PQ-)?1[0-9A-Z]+`), redactedText},
+	{regexp.MustCompile(`(?i)AGE-PLUGIN-[0-9A-Z-]+-1[0-9A-Z]+`), redactedText},
+	// SSH identities and service account keys, possibly JSON-escaped.
+	{regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`), redactedText},
+	// Ciphertext carries the lengths and structure of the plaintext.
+	{regexp.MustCompile(`ENC\[[^\]]*\]`), "ENC[" + redactedText + "]"},
+}
+
+// redactSecrets scrubs anything matching secretPatterns from s.
+func redactSecrets(s string) string {
+	for _, p := range secretPatterns {
+		s = p.pattern.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// redactor scrubs the known plaintext values and credentials of one
+// operation from errors and logs, in addition to secretPatterns.
+type redactor struct {
+	values      []string
+	shortValues []*regexp.Regexp
+}
+
+func newRedactor(values ...string) *redactor {
+	seen := make(map[string]bool)
+	r := &redactor{}
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		if len(value) < minRedactedValueLength {
+			quoted := regexp.QuoteMeta(value)
+			r.shortValues = append(r.shortValues,
+				regexp.MustCompile(`(["'])`+quoted+`(["'])`),
+				regexp.MustCompile(`(?m)([:=][ \t]*)`+quoted+`([ \t]*(?:[,;}\]]|$))`),
+			)
+			continue
+		}
+		r.values = append(r.values, value)
+	}
+	// Longer values first, so a value containing another is redacted whole.
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r
+}
+
+func (r *redactor) redact(s string) string {
+	s = redactSecrets(s)
+	if r == nil {
+		return s
+	}
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, redactedText)
+	}
+	for _, pattern := range r.shortValues {
+		s = pattern.ReplaceAllString(s, "${1}"+redactedText+"${2}")
+	}
+	return s
+}
+
+// logContext masks the same secrets in messages and fields logged with the
+// returned context, by the root logger and the sops subsystem.
+func (r *redactor) logContext(ctx context.Context) context.Context {
+	patterns := make([]*regexp.Regexp, len(secretPatterns))
+	for i, p := range secretPatterns {
+		patterns[i] = p.pattern
+	}
+	var values []string
+	if r != nil {
+		values = r.values
+		patterns = append(patterns, r.shortValues...)
+	}
+
+	ctx = tflog.MaskMessageRegexes(ctx, patterns...)
+	ctx = tflog.MaskAllFieldValuesRegexes(ctx, patterns...)
+	ctx = tflog.SubsystemMaskMessageRegexes(ctx, sopsLogSubsystem, patterns...)
+	ctx = tflog.SubsystemMaskAllFieldValuesRegexes(ctx, sopsLogSubsystem, patterns...)
+	if len(values) > 0 {
+		ctx = tflog.MaskMessageStrings(ctx, values...)
+		ctx = tflog.MaskAllFieldValuesStrings(ctx, values...)
+		ctx = tflog.SubsystemMaskMessageStrings(ctx, sopsLogSubsystem, values...)
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, sopsLogSubsystem, values...)
+	}
+	return ctx
+}
+
+// plaintextValues returns the string and number values of a JSON document,
+// leaving out its keys. It returns what it could read of a malformed
+// document.
+func plaintextValues(data []byte) []string {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	type level struct{ object, key bool }
+	var stack []level
+	var values []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return values
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if n := len(stack); n > 0 && stack[n-1].object {
+			if stack[n-1].key {
+				stack[n-1].key = false
+				continue
+			}
+			stack[n-1].key = true
+		}
+
+		switch token := token.(type) {
+		case json.Delim:
+			stack = append(stack, level{object: token == '{', key: token == '{'})
+		case string:
+			values = append(values, token)
+		case json.Number:
+			values = append(values, token.String())
+		}
+	}
+}
+
+// binaryPlaintextValues returns an opaque payload whole and line by line, so
+// that a line of it echoed by sops is redacted too.
+func binaryPlaintextValues(data []byte) []string {
+	values := []string{string(data)}
+	for _, line := range strings.Split(string(data), "\n") {
+		values = append(values, strings.TrimSpace(line))
+	}
+	return values
+}
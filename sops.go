@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -19,19 +19,352 @@ func formatSopsStderr(stderr string) string {
 	return ": " + stderr
 }
 
+// SopsBackendOptions holds provider-level settings that apply to both
+// encryption and decryption: the sops binary and key backend settings.
+type SopsBackendOptions struct {
+	Sops *SopsBinary
+
+	// ConfigPath is the .sops.yaml used for decryption and for encryptions
+	// that set a Filename.
+	ConfigPath string
+
+	GnuPGHome          string
+	VaultAddress       string
+	VaultToken         string
+	AWSRegion          string
+	AWSProfile         string
+	AWSKMSEndpoint     string
+	GCPCredentials     string
+	GCPAccessToken     string
+	GCPKMSEndpoint     string
+	AzureTenantID      string
+	AzureClientID      string
+	AzureClientSecret  string
+	AzureAuthorityHost string
+
+	KeyServiceAddresses    []string
+	DisableLocalKeyService bool
+
+	// IsolateEnvironment starts sops from Environment alone instead of the
+	// Terraform process environment.
+	IsolateEnvironment bool
+	Environment        map[string]string
+}
+
+// args returns the keyservice flags. Keys are then wrapped and unwrapped by
+// the listed sops keyservice servers, optionally without the local one.
+func (o SopsBackendOptions) args() []string {
+	var args []string
+	for _, address := range o.KeyServiceAddresses {
+		args = append(args, "--keyservice", address)
+	}
+	if o.DisableLocalKeyService {
+		args = append(args, "--enable-local-keyservice=false")
+	}
+	return args
+}
+
+// configPath resolves ConfigPath, or /dev/null to ignore any .sops.yaml.
+func (o SopsBackendOptions) configPath() (string, error) {
+	if o.ConfigPath == "" {
+		return "/dev/null", nil
+	}
+
+	configPath, err := expandTilde(o.ConfigPath)
+	if err == nil {
+		configPath, err = filepath.Abs(configPath)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve config path %q: %w", o.ConfigPath, err)
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("sops config file not found: %s", configPath)
+		}
+		return "", fmt.Errorf("failed to access sops config file %s: %w", configPath, err)
+	}
+	return configPath, nil
+}
+
+// isolatedEnvironmentVariables are kept when the environment is not
+// inherited, so sops can still find age plugins and gpg and write temporary
+// files.
+var isolatedEnvironmentVariables = []string{"PATH", "TMPDIR"}
+
+// baseEnviron returns the environment sops starts from, before the backend
+// settings and keys of an operation are added.
+func (o SopsBackendOptions) baseEnviron() []string {
+	var env []string
+	if o.IsolateEnvironment {
+		for _, name := range isolatedEnvironmentVariables {
+			if value, ok := os.LookupEnv(name); ok {
+				env = append(env, name+"="+value)
+			}
+		}
+	} else {
+		env = os.Environ()
+	}
+
+	names := make([]string, 0, len(o.Environment))
+	for name := range o.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+o.Environment[name])
+	}
+	return env
+}
+
+// secrets returns the credentials among the backend settings, which are
+// redacted from errors and logs.
+func (o SopsBackendOptions) secrets() []string {
+	secrets := []string{o.VaultToken, o.GCPAccessToken, o.AzureClientSecret}
+	// GOOGLE_CREDENTIALS is either a path or the key itself.
+	if strings.HasPrefix(strings.TrimSpace(o.GCPCredentials), "{") {
+		secrets = append(secrets, o.GCPCredentials)
+	}
+	for name, value := range o.Environment {
+		if credentialVariableName.MatchString(name) {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// credentialVariableName matches the names of environment variables whose
+// values are redacted, such as AWS_SECRET_ACCESS_KEY, VAULT_TOKEN or
+// SOPS_AGE_KEY. Others, such as HOME or AWS_PROFILE, are left alone so they
+// do not blank out unrelated text.
+var credentialVariableName = regexp.MustCompile(`(?i)KEY|SECRET|TOKEN|PASSWORD|PASSPHRASE|CREDENTIAL`)
+
+// features returns the sops features the backend settings need.
+func (o SopsBackendOptions) features() []sopsFeature {
+	features := keyServiceFeatures(o.KeyServiceAddresses)
+	if o.GCPKMSEndpoint != "" {
+		features = append(features, sopsFeatureGCPKMSEndpoint)
+	}
+	return features
+}
+
+func (o SopsBackendOptions) environ() ([]string, error) {
+	var env []string
+
+	if o.GnuPGHome != "" {
+		gnupgHome, err := expandTilde(o.GnuPGHome)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve GnuPG home directory %q: %w", o.GnuPGHome, err)
+		}
+		if _, err := os.Stat(gnupgHome); err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("GnuPG home directory not found: %s", gnupgHome)
+			}
+			return nil, fmt.Errorf("failed to access GnuPG home directory %s: %w", gnupgHome, err)
+		}
+		env = append(env, "GNUPGHOME="+gnupgHome)
+	}
+
+	if o.VaultAddress != "" {
+		env = append(env, "VAULT_ADDR="+o.VaultAddress)
+	}
+
+	if o.VaultToken != "" {
+		env = append(env, "VAULT_TOKEN="+o.VaultToken)
+	}
+
+	if o.AWSRegion != "" {
+		env = append(env, "AWS_REGION="+o.AWSRegion)
+	}
+
+	if o.AWSProfile != "" {
+		env = append(env, "AWS_PROFILE="+o.AWSProfile)
+	}
+
+	// The AWS SDK honours service-specific endpoint overrides.
+	if o.AWSKMSEndpoint != "" {
+		env = append(env, "AWS_ENDPOINT_URL_KMS="+o.AWSKMSEndpoint)
+	}
+
+	// SOPS reads GOOGLE_CREDENTIALS as either a file path or JSON content.
+	if o.GCPCredentials != "" {
+		env = append(env, "GOOGLE_CREDENTIALS="+o.GCPCredentials)
+	}
+
+	if o.GCPAccessToken != "" {
+		env = append(env, "GOOGLE_OAUTH_ACCESS_TOKEN="+o.GCPAccessToken)
+	}
+
+	if o.GCPKMSEndpoint != "" {
+		env = append(env, "SOPS_GCP_KMS_ENDPOINT="+o.GCPKMSEndpoint)
+	}
+
+	if o.AzureTenantID != "" {
+		env = append(env, "AZURE_TENANT_ID="+o.AzureTenantID)
+	}
+
+	if o.AzureClientID != "" {
+		env = append(env, "AZURE_CLIENT_ID="+o.AzureClientID)
+	}
+
+	if o.AzureClientSecret != "" {
+		env = append(env, "AZURE_CLIENT_SECRET="+o.AzureClientSecret)
+	}
+
+	if o.AzureAuthorityHost != "" {
+		env = append(env, "AZURE_AUTHORITY_HOST="+o.AzureAuthorityHost)
+	}
+
+	return env, nil
+}
+
 type SopsEncryptOptions struct {
-	AgeRecipients     []string
-	OutputType        string
-	OutputIndent      *int64
-	UnencryptedSuffix *string
-	EncryptedSuffix   *string
-	UnencryptedRegex  *string
-	EncryptedRegex    *string
+	AgeRecipients        []string
+	PGPFingerprints      []string
+	HCVaultTransitURIs   []string
+	KMSARNs              []string
+	KMSRole              string
+	KMSEncryptionContext map[string]string
+	GCPKMSResourceIDs    []string
+	AzureKVURLs          []string
+	KeyGroups            []SopsKeyGroup
+	ShamirThreshold      *int64
+	OutputType           string
+	OutputIndent         *int64
+	UnencryptedSuffix    *string
+	EncryptedSuffix      *string
+	UnencryptedRegex     *string
+	EncryptedRegex       *string
+	Filename             string
+	Backend              SopsBackendOptions
+}
+
+func (o SopsEncryptOptions) recipientCount() int {
+	return len(o.AgeRecipients) + len(o.PGPFingerprints) + len(o.HCVaultTransitURIs) + len(o.KMSARNs) +
+		len(o.GCPKMSResourceIDs) + len(o.AzureKVURLs)
+}
+
+// kmsKeys renders the ARNs in the "arn+role" form SOPS uses to assume a role
+// before calling KMS.
+func (o SopsEncryptOptions) kmsKeys() []string {
+	keys := make([]string, len(o.KMSARNs))
+	for i, arn := range o.KMSARNs {
+		keys[i] = arn
+		if o.KMSRole != "" {
+			keys[i] += "+" + o.KMSRole
+		}
+	}
+	return keys
+}
+
+// ageRecipients returns the age recipients, including those in key groups.
+func (o SopsEncryptOptions) ageRecipients() []string {
+	recipients := append([]string{}, o.AgeRecipients...)
+	for _, group := range o.KeyGroups {
+		recipients = append(recipients, group.AgeRecipients...)
+	}
+	return recipients
+}
+
+// agePluginNames returns the plugins needed to encrypt to the age recipients.
+func (o SopsEncryptOptions) agePluginNames() []string {
+	var names []string
+	for _, recipient := range o.ageRecipients() {
+		if name := agePluginRecipientName(recipient); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sopsFormats are the document formats SOPS reads and writes.
+var sopsFormats = []string{"json", "yaml", "dotenv", "ini"}
+
+// sopsEncryptInputTypes are the input types of sops_encrypt: a structured
+// input converted to JSON, or an opaque binary payload.
+var sopsEncryptInputTypes = []string{"json", "binary"}
+
+// sopsDecryptInputTypes are the formats sops_decrypt reads. "binary" reads
+// the JSON documents sops writes for binary payloads.
+var sopsDecryptInputTypes = append(append([]string{}, sopsFormats...), "binary")
+
+// sopsDecryptOutputTypes are the output types of sops_decrypt: the data
+// structure as JSON, or the payload of a binary encryption.
+var sopsDecryptOutputTypes = []string{"json", "binary"}
+
+// checkOutputShape checks that input can be written as outputType. dotenv
+// holds a flat map of strings, and ini a map of sections that each hold a
+// map of strings.
+func checkOutputShape(outputType string, input map[string]interface{}) error {
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch outputType {
+	case "dotenv":
+		for _, key := range keys {
+			if _, ok := input[key].(string); !ok {
+				return fmt.Errorf("dotenv output requires a flat map of strings, but %q is %s", key, describeShape(input[key]))
+			}
+		}
+	case "ini":
+		for _, key := range keys {
+			section, ok := input[key].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("ini output requires a map of sections, but %q is %s", key, describeShape(input[key]))
+			}
+			names := make([]string, 0, len(section))
+			for name := range section {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if _, ok := section[name].(string); !ok {
+					return fmt.Errorf("ini sections must hold strings, but %q in section %q is %s", name, key, describeShape(section[name]))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// describeShape names the kind of a converted input value for errors.
+func describeShape(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "a map"
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	case bool:
+		return "a bool"
+	case nil:
+		return "null"
+	}
+	return "a number"
+}
+
+func formatEncryptionContext(encryptionContext map[string]string) string {
+	pairs := make([]string, 0, len(encryptionContext))
+	for key, value := range encryptionContext {
+		pairs = append(pairs, key+":"+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (o SopsEncryptOptions) outputType() string {
+	if o.OutputType == "" {
+		return "json"
+	}
+	return o.OutputType
 }
 
 func encryptWithSops(ctx context.Context, input map[string]interface{}, opts SopsEncryptOptions) ([]byte, error) {
-	if len(opts.AgeRecipients) == 0 {
-		return nil, fmt.Errorf("at least one age recipient must be provided")
+	if err := checkOutputShape(opts.outputType(), input); err != nil {
+		return nil, err
 	}
 
 	inputJSON, err := json.Marshal(input)
@@ -39,12 +372,88 @@ func encryptWithSops(ctx context.Context, input map[string]interface{}, opts Sop
 		return nil, fmt.Errorf("failed to marshal input to JSON: %w", err)
 	}
 
-	outputType := opts.OutputType
-	if outputType == "" {
-		outputType = "json"
+	return encryptDocumentWithSops(ctx, inputJSON, "json", plaintextValues(inputJSON), opts)
+}
+
+// encryptBinaryWithSops encrypts an opaque payload in the sops binary format,
+// which stores it as a single encrypted "data" string.
+func encryptBinaryWithSops(ctx context.Context, data []byte, opts SopsEncryptOptions) ([]byte, error) {
+	if err := checkOutputShape(opts.outputType(), map[string]interface{}{"data": ""}); err != nil {
+		return nil, fmt.Errorf("binary input is stored as a single \"data\" string: %w", err)
+	}
+
+	return encryptDocumentWithSops(ctx, data, "binary", binaryPlaintextValues(data), opts)
+}
+
+// encryptDocumentWithSops encrypts a document in inputType. plaintext lists
+// the values to redact from errors and logs.
+func encryptDocumentWithSops(ctx context.Context, document []byte, inputType string, plaintext []string, opts SopsEncryptOptions) ([]byte, error) {
+	if len(opts.KeyGroups) > 0 {
+		if opts.recipientCount() > 0 {
+			return nil, fmt.Errorf("key groups cannot be combined with top-level recipients")
+		}
+		for i, group := range opts.KeyGroups {
+			if group.keyCount() == 0 {
+				return nil, fmt.Errorf("key group %d must contain at least one key", i)
+			}
+		}
+		if opts.Filename != "" {
+			return nil, fmt.Errorf("key groups cannot be combined with a filename")
+		}
+	} else if opts.recipientCount() == 0 && opts.Filename == "" {
+		return nil, fmt.Errorf("at least one recipient must be provided")
+	}
+
+	if opts.Filename != "" && opts.Backend.ConfigPath == "" {
+		return nil, fmt.Errorf("filename %q requires config_path to be set on the provider", opts.Filename)
+	}
+
+	if opts.ShamirThreshold != nil && (*opts.ShamirThreshold < 1 || *opts.ShamirThreshold > int64(len(opts.KeyGroups))) {
+		return nil, fmt.Errorf("shamir threshold must be between 1 and the number of key groups (%d)", len(opts.KeyGroups))
+	}
+
+	features := append(ageRecipientFeatures(opts.ageRecipients()), opts.Backend.features()...)
+	if err := opts.Backend.Sops.requireFeatures(features...); err != nil {
+		return nil, err
+	}
+
+	if err := requireAgePlugins(opts.agePluginNames()); err != nil {
+		return nil, err
 	}
 
-	args := []string{"--config", "/dev/null"}
+	outputType := opts.outputType()
+	redact := newRedactor(append(plaintext, opts.Backend.secrets()...)...)
+	ctx = newSopsLogContext(ctx, redact)
+
+	var err error
+	configPath := "/dev/null"
+	switch {
+	case len(opts.KeyGroups) > 0:
+		configPath, err = writeKeyGroupsConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(configPath)
+	case opts.Filename != "":
+		configPath, err = opts.Backend.configPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args := []string{"--config", configPath}
+
+	// SOPS picks the creation rule whose path_regex matches the filename,
+	// relative to the directory of the config file. Explicit recipients and
+	// key selection flags still take precedence over the rule, as with the
+	// sops CLI.
+	if opts.Filename != "" {
+		filename := opts.Filename
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(filepath.Dir(configPath), filename)
+		}
+		args = append(args, "--filename-override", filename)
+	}
 
 	if opts.OutputIndent != nil {
 		args = append(args, "--indent", fmt.Sprintf("%d", *opts.OutputIndent))
@@ -66,22 +475,64 @@ func encryptWithSops(ctx context.Context, input map[string]interface{}, opts Sop
 		args = append(args, "--encrypted-regex", *opts.EncryptedRegex)
 	}
 
-	args = append(args, "--encrypt", "--input-type", "json", "--output-type", outputType, "/dev/stdin")
-	cmd := exec.CommandContext(ctx, sopsBinary, args...)
-	cmd.Stdin = bytes.NewReader(inputJSON)
+	// Key group KMS keys carry their encryption context in the config file.
+	if len(opts.KMSEncryptionContext) > 0 && len(opts.KeyGroups) == 0 {
+		args = append(args, "--encryption-context", formatEncryptionContext(opts.KMSEncryptionContext))
+	}
+
+	args = append(args, opts.Backend.args()...)
+	args = append(args, "--encrypt", "--input-type", inputType, "--output-type", outputType, "/dev/stdin")
+
+	backendEnv, err := opts.Backend.environ()
+	if err != nil {
+		return nil, err
+	}
 
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "SOPS_AGE_RECIPIENTS="+strings.Join(opts.AgeRecipients, ","))
+	env := opts.Backend.baseEnviron()
+	env = append(env, backendEnv...)
+	if len(opts.AgeRecipients) > 0 {
+		env = append(env, "SOPS_AGE_RECIPIENTS="+strings.Join(opts.AgeRecipients, ","))
+	}
+	if len(opts.PGPFingerprints) > 0 {
+		env = append(env, "SOPS_PGP_FP="+strings.Join(opts.PGPFingerprints, ","))
+	}
+	if len(opts.HCVaultTransitURIs) > 0 {
+		env = append(env, "SOPS_VAULT_URIS="+strings.Join(opts.HCVaultTransitURIs, ","))
+	}
+	if len(opts.KMSARNs) > 0 {
+		env = append(env, "SOPS_KMS_ARN="+strings.Join(opts.kmsKeys(), ","))
+	}
+	if len(opts.GCPKMSResourceIDs) > 0 {
+		env = append(env, "SOPS_GCP_KMS_IDS="+strings.Join(opts.GCPKMSResourceIDs, ","))
+	}
+	if len(opts.AzureKVURLs) > 0 {
+		env = append(env, "SOPS_AZURE_KEYVAULT_URLS="+strings.Join(opts.AzureKVURLs, ","))
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	recipientCount := opts.recipientCount()
+	for _, group := range opts.KeyGroups {
+		recipientCount += group.keyCount()
+	}
+	operationLog := startSopsOperationLog(ctx, map[string]interface{}{
+		"operation":       "encrypt",
+		"input_type":      inputType,
+		"output_type":     outputType,
+		"recipient_count": recipientCount,
+		"key_group_count": len(opts.KeyGroups),
+		"creation_rule":   opts.Filename != "",
+		"input_bytes":     len(document),
+	})
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("sops encrypt failed: %w%s", err, formatSopsStderr(stderr.String()))
+	stdout, stderr, err := opts.Backend.Sops.run(ctx, args, env, document)
+	if err != nil {
+		err = newSopsError("encrypt", err, stderr, redact)
+	}
+	operationLog.finish(ctx, stdout, err)
+	if err != nil {
+		return nil, err
 	}
 
-	return stdout.Bytes(), nil
+	return stdout, nil
 }
 
 func expandTilde(path string) (string, error) {
@@ -97,10 +548,104 @@ func expandTilde(path string) (string, error) {
 	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
 }
 
+// ageIdentityFile is an identity file read ahead of running SOPS.
+type ageIdentityFile struct {
+	Path       string
+	Identities string
+	Encrypted  bool
+}
+
+func readAgeIdentityFile(path, passphrase string) (ageIdentityFile, error) {
+	identityPath, err := expandTilde(path)
+	if err != nil {
+		return ageIdentityFile{}, fmt.Errorf("failed to resolve age identity file path %q: %w", path, err)
+	}
+	if _, err := os.Stat(identityPath); err != nil {
+		if os.IsNotExist(err) {
+			return ageIdentityFile{}, fmt.Errorf("age identity file not found: %s", identityPath)
+		}
+		return ageIdentityFile{}, fmt.Errorf("failed to access age identity file %s: %w", identityPath, err)
+	}
+	identities, err := os.ReadFile(identityPath)
+	if err != nil {
+		return ageIdentityFile{}, fmt.Errorf("failed to read age identity file %s: %w", identityPath, err)
+	}
+
+	if !isEncryptedAgeFile(identities) {
+		return ageIdentityFile{Path: identityPath, Identities: string(identities)}, nil
+	}
+
+	if passphrase == "" {
+		return ageIdentityFile{}, fmt.Errorf("age identity file %s is passphrase-protected; set age_identity_passphrase to unlock it", identityPath)
+	}
+	decrypted, err := decryptAgeIdentityFile(identities, passphrase)
+	if err != nil {
+		return ageIdentityFile{}, fmt.Errorf("%s: %w", identityPath, err)
+	}
+	return ageIdentityFile{Path: identityPath, Identities: decrypted, Encrypted: true}, nil
+}
+
 type SopsDecryptOptions struct {
-	AgeIdentityPath  string
-	AgeIdentityValue string
-	InputType        string
+	AgeIdentityPath       string
+	AgeIdentityValue      string
+	AgeSSHKeyPath         string
+	AgeIdentityPassphrase string
+	AgeIdentityPaths      []string
+	AgeIdentityValues     []string
+	InputType             string
+	OutputType            string
+	Backend               SopsBackendOptions
+}
+
+// secrets returns the identities, passphrase and backend credentials of the
+// decryption, which are redacted from errors and logs.
+func (o SopsDecryptOptions) secrets() []string {
+	secrets := []string{o.AgeIdentityValue, o.AgeIdentityPassphrase}
+	secrets = append(secrets, o.AgeIdentityValues...)
+	return append(secrets, o.Backend.secrets()...)
+}
+
+// ageIdentityEnv hands the configured age identities to SOPS. A single plain
+// identity file is passed by path; anything else is combined into
+// SOPS_AGE_KEY, which SOPS reads as one identity per line. Unwrapped
+// passphrase-protected files therefore never touch the disk in plaintext.
+func (o SopsDecryptOptions) ageIdentityEnv() ([]string, error) {
+	var values, paths []string
+	if o.AgeIdentityValue != "" {
+		values = append(values, o.AgeIdentityValue)
+	} else if o.AgeIdentityPath != "" {
+		paths = append(paths, o.AgeIdentityPath)
+	}
+	values = append(values, o.AgeIdentityValues...)
+	paths = append(paths, o.AgeIdentityPaths...)
+
+	files := make([]ageIdentityFile, 0, len(paths))
+	for _, path := range paths {
+		file, err := readAgeIdentityFile(path, o.AgeIdentityPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		values = append(values, file.Identities)
+	}
+
+	for _, value := range values {
+		if err := o.Backend.Sops.requireFeatures(ageIdentityFeatures(value)...); err != nil {
+			return nil, err
+		}
+		if err := requireAgePlugins(agePluginIdentityNames(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case len(values) == 0:
+		return nil, nil
+	case len(files) == 1 && len(values) == 1 && !files[0].Encrypted:
+		return []string{"SOPS_AGE_KEY_FILE=" + files[0].Path}, nil
+	default:
+		return []string{"SOPS_AGE_KEY=" + strings.Join(values, "\n")}, nil
+	}
 }
 
 func decryptWithSops(ctx context.Context, encryptedData []byte, opts SopsDecryptOptions) ([]byte, error) {
@@ -109,34 +654,74 @@ func decryptWithSops(ctx context.Context, encryptedData []byte, opts SopsDecrypt
 		return nil, fmt.Errorf("input type is required")
 	}
 
-	args := []string{"--config", "/dev/null", "decrypt", "--input-type", inputType, "--output-type", "json", "/dev/stdin"}
-	cmd := exec.CommandContext(ctx, sopsBinary, args...)
-	cmd.Stdin = bytes.NewReader(encryptedData)
+	redact := newRedactor(opts.secrets()...)
+	ctx = newSopsLogContext(ctx, redact)
 
-	cmd.Env = os.Environ()
-	if opts.AgeIdentityValue != "" {
-		cmd.Env = append(cmd.Env, "SOPS_AGE_KEY="+opts.AgeIdentityValue)
-	} else if opts.AgeIdentityPath != "" {
-		identityPath, err := expandTilde(opts.AgeIdentityPath)
+	if err := opts.Backend.Sops.requireFeatures(opts.Backend.features()...); err != nil {
+		return nil, err
+	}
+	if opts.AgeSSHKeyPath != "" {
+		if err := opts.Backend.Sops.requireFeatures(sopsFeatureAgeSSH); err != nil {
+			return nil, err
+		}
+	}
+
+	configPath, err := opts.Backend.configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Keyservice flags belong to the decrypt subcommand.
+	args := []string{"--config", configPath, "decrypt"}
+	args = append(args, opts.Backend.args()...)
+	outputType := opts.OutputType
+	if outputType == "" {
+		outputType = "json"
+	}
+	args = append(args, "--input-type", inputType, "--output-type", outputType, "/dev/stdin")
+
+	backendEnv, err := opts.Backend.environ()
+	if err != nil {
+		return nil, err
+	}
+
+	env := opts.Backend.baseEnviron()
+	env = append(env, backendEnv...)
+	identityEnv, err := opts.ageIdentityEnv()
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, identityEnv...)
+
+	if opts.AgeSSHKeyPath != "" {
+		sshKeyPath, err := expandTilde(opts.AgeSSHKeyPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve age identity file path %q: %w", opts.AgeIdentityPath, err)
+			return nil, fmt.Errorf("failed to resolve SSH private key path %q: %w", opts.AgeSSHKeyPath, err)
 		}
-		if _, err := os.Stat(identityPath); err != nil {
+		if _, err := os.Stat(sshKeyPath); err != nil {
 			if os.IsNotExist(err) {
-				return nil, fmt.Errorf("age identity file not found: %s", identityPath)
+				return nil, fmt.Errorf("SSH private key file not found: %s", sshKeyPath)
 			}
-			return nil, fmt.Errorf("failed to access age identity file %s: %w", identityPath, err)
+			return nil, fmt.Errorf("failed to access SSH private key file %s: %w", sshKeyPath, err)
 		}
-		cmd.Env = append(cmd.Env, "SOPS_AGE_KEY_FILE="+identityPath)
+		env = append(env, "SOPS_AGE_SSH_PRIVATE_KEY_FILE="+sshKeyPath)
 	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	operationLog := startSopsOperationLog(ctx, map[string]interface{}{
+		"operation":   "decrypt",
+		"input_type":  inputType,
+		"output_type": outputType,
+		"input_bytes": len(encryptedData),
+	})
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("sops decrypt failed: %w%s", err, formatSopsStderr(stderr.String()))
+	stdout, stderr, err := opts.Backend.Sops.run(ctx, args, env, encryptedData)
+	if err != nil {
+		err = newSopsError("decrypt", err, stderr, redact)
+	}
+	operationLog.finish(ctx, stdout, err)
+	if err != nil {
+		return nil, err
 	}
 
-	return stdout.Bytes(), nil
+	return stdout, nil
 }
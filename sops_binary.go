@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+type sopsVersion struct {
+	Major, Minor, Patch int
+}
+
+func (v sopsVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func (v sopsVersion) atLeast(other sopsVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+// defaultSopsOperationTimeout applies when operation_timeout is not set.
+const defaultSopsOperationTimeout = 5 * time.Minute
+
+// sopsProbeTimeout bounds sops --version, which needs no keys or network.
+const sopsProbeTimeout = 30 * time.Second
+
+// sopsWaitDelay is how long to wait for a killed sops process to release its
+// output, for example when a gpg-agent it started still holds stderr open.
+const sopsWaitDelay = 5 * time.Second
+
+// minSopsVersion is the oldest release with the decrypt subcommand and the
+// --indent flag the provider relies on.
+var minSopsVersion = sopsVersion{3, 9, 0}
+
+// sopsFeature is a capability only newer sops releases have.
+type sopsFeature struct {
+	name  string
+	since sopsVersion
+}
+
+var (
+	sopsFeatureAgeSSH         = sopsFeature{"age SSH keys", sopsVersion{3, 10, 0}}
+	sopsFeatureAgePlugins     = sopsFeature{"age plugins", sopsVersion{3, 10, 0}}
+	sopsFeatureAgeHybrid      = sopsFeature{"post-quantum age keys", sopsVersion{3, 12, 0}}
+	sopsFeatureUnixKeyService = sopsFeature{"keyservice unix sockets", sopsVersion{3, 11, 0}}
+	sopsFeatureGCPKMSEndpoint = sopsFeature{"GCP KMS endpoint overrides", sopsVersion{3, 13, 0}}
+)
+
+var sopsVersionRegex = regexp.MustCompile(`(?m)^sops (\d+)\.(\d+)\.(\d+)`)
+
+func parseSopsVersion(output string) (sopsVersion, error) {
+	match := sopsVersionRegex.FindStringSubmatch(output)
+	if match == nil {
+		return sopsVersion{}, fmt.Errorf("could not find a version in %q", strings.TrimSpace(output))
+	}
+
+	var parts [3]int
+	for i := range parts {
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return sopsVersion{}, fmt.Errorf("invalid version number %q: %w", match[i+1], err)
+		}
+		parts[i] = n
+	}
+
+	return sopsVersion{parts[0], parts[1], parts[2]}, nil
+}
+
+// SopsBinary is the sops executable resolved when the provider is
+// configured. A nil *SopsBinary runs sopsBinary from PATH without version
+// checks.
+type SopsBinary struct {
+	Path    string
+	Version sopsVersion
+	SHA256  string
+
+	// pinned binaries are checked again before each run, in case the file
+	// was replaced after the provider was configured.
+	pinned bool
+	info   os.FileInfo
+
+	// Timeout bounds each sops process, and slots, when set, limits how many
+	// run at once.
+	Timeout time.Duration
+	slots   chan struct{}
+
+	// err is reported by every operation when the binary was not set
+	// explicitly, so configurations that never run sops still work without
+	// it installed.
+	err error
+}
+
+// probeSopsBinary resolves name on PATH and checks its version, running it
+// with env, or the provider's own environment when env is nil. When
+// wantSHA256 is set, the binary is not run unless its digest matches.
+func probeSopsBinary(ctx context.Context, name, wantSHA256 string, env []string) (*SopsBinary, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("sops binary not found; install sops or set sops_binary_path: %w", err)
+	}
+
+	pinned := wantSHA256 != ""
+	if pinned {
+		// Run the file that was hashed rather than whatever a symlink points
+		// to later.
+		path, err = filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve sops binary %s: %w", name, err)
+		}
+	}
+
+	digest, info, err := hashSopsBinary(path)
+	if err != nil {
+		return nil, err
+	}
+	tflog.Info(ctx, "Resolved sops binary", map[string]interface{}{
+		"path":   path,
+		"sha256": digest,
+	})
+	if pinned && !strings.EqualFold(digest, wantSHA256) {
+		return nil, fmt.Errorf("refusing to run %s: its SHA-256 digest is %s, but sops_binary_sha256 is %s", path, digest, strings.ToLower(wantSHA256))
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, sopsProbeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(probeCtx, path, "--version")
+	cmd.WaitDelay = sopsWaitDelay
+	if env == nil {
+		env = os.Environ()
+	}
+	// Older releases query GitHub for the latest version unless told not to.
+	cmd.Env = append(env[:len(env):len(env)], "SOPS_DISABLE_VERSION_CHECK=true")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s --version: %w", path, err)
+	}
+
+	version, err := parseSopsVersion(string(output))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the version of %s: %w", path, err)
+	}
+
+	if !version.atLeast(minSopsVersion) {
+		return nil, fmt.Errorf("%s is sops %s, but the provider requires sops %s or later", path, version, minSopsVersion)
+	}
+
+	return &SopsBinary{Path: path, Version: version, SHA256: digest, pinned: pinned, info: info}, nil
+}
+
+func hashSopsBinary(path string) (string, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open sops binary %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to access sops binary %s: %w", path, err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", nil, fmt.Errorf("failed to read sops binary %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), info, nil
+}
+
+// verify re-hashes a pinned binary whose file changed since it was probed.
+func (b *SopsBinary) verify() error {
+	if !b.pinned {
+		return nil
+	}
+
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return fmt.Errorf("failed to access sops binary %s: %w", b.Path, err)
+	}
+	if os.SameFile(info, b.info) && info.Size() == b.info.Size() && info.ModTime().Equal(b.info.ModTime()) {
+		return nil
+	}
+
+	digest, _, err := hashSopsBinary(b.Path)
+	if err != nil {
+		return err
+	}
+	if digest != b.SHA256 {
+		return fmt.Errorf("refusing to run %s: it was replaced after the provider was configured and its SHA-256 digest is now %s", b.Path, digest)
+	}
+	return nil
+}
+
+// setMaxConcurrent limits the number of sops processes run at once.
+func (b *SopsBinary) setMaxConcurrent(n int) {
+	b.slots = make(chan struct{}, n)
+}
+
+// run runs sops with args, env and stdin once a slot is free, and returns
+// its stdout and stderr. Waiting for a slot does not count towards the
+// timeout.
+func (b *SopsBinary) run(ctx context.Context, args, env []string, stdin []byte) ([]byte, []byte, error) {
+	path := sopsBinary
+	var timeout time.Duration
+	if b != nil {
+		if b.err != nil {
+			return nil, nil, b.err
+		}
+		if err := b.verify(); err != nil {
+			return nil, nil, err
+		}
+		path = b.Path
+		timeout = b.Timeout
+
+		if b.slots != nil {
+			select {
+			case b.slots <- struct{}{}:
+				defer func() { <-b.slots }()
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+	}
+
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Only the names are logged, since values include keys and credentials.
+	tflog.Debug(ctx, "Running sops", map[string]interface{}{
+		"path":        path,
+		"environment": environmentNames(env),
+	})
+
+	cmd := exec.CommandContext(runCtx, path, args...)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.WaitDelay = sopsWaitDelay
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s; raise operation_timeout if sops needs longer", timeout)
+	}
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// environmentNames returns the sorted, distinct variable names in env.
+func environmentNames(env []string) []string {
+	seen := make(map[string]bool, len(env))
+	names := make([]string, 0, len(env))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requireFeatures fails with the first feature the binary is too old for.
+func (b *SopsBinary) requireFeatures(features ...sopsFeature) error {
+	if b == nil || b.err != nil {
+		return nil
+	}
+
+	for _, feature := range features {
+		if !b.Version.atLeast(feature.since) {
+			return fmt.Errorf("%s require sops %s or later, but %s is sops %s", feature.name, feature.since, b.Path, b.Version)
+		}
+	}
+	return nil
+}
+
+// ageRecipientFeatures returns the sops features needed to encrypt to the
+// given age recipients.
+func ageRecipientFeatures(recipients []string) []sopsFeature {
+	var features []sopsFeature
+	for _, recipient := range recipients {
+		switch {
+		case strings.HasPrefix(recipient, "ssh-"):
+			features = append(features, sopsFeatureAgeSSH)
+		case strings.HasPrefix(recipient, "age1pq1"):
+			features = append(features, sopsFeatureAgeHybrid)
+		case agePluginRecipientName(recipient) != "":
+			features = append(features, sopsFeatureAgePlugins)
+		}
+	}
+	return features
+}
+
+// ageIdentityFeatures returns the sops features needed to decrypt with the
+// given identity file content.
+func ageIdentityFeatures(identities string) []sopsFeature {
+	var features []sopsFeature
+	for _, line := range strings.Split(identities, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "AGE-SECRET-KEY-PQ-"):
+			features = append(features, sopsFeatureAgeHybrid)
+		case strings.HasPrefix(line, "AGE-PLUGIN-"):
+			features = append(features, sopsFeatureAgePlugins)
+		}
+	}
+	return features
+}
+
+func keyServiceFeatures(addresses []string) []sopsFeature {
+	for _, address := range addresses {
+		if strings.HasPrefix(address, "unix://") {
+			return []sopsFeature{sopsFeatureUnixKeyService}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testFakeSopsBinary writes a fake sops that reports the given version, which
+// may refer to environment variables, and runs body, a shell script, for every
+// other invocation.
+func testFakeSopsBinary(t *testing.T, version, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sops")
+	script := fmt.Sprintf("#!/bin/sh\nif [ \"$1\" = --version ]; then echo \"sops %s (latest)\"; exit 0; fi\n%s\n", version, body)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testFileSHA256(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseSopsVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   sopsVersion
+	}{
+		{"sops 3.9.0 (latest)\n", sopsVersion{3, 9, 0}},
+		{"sops 3.10.2\n", sopsVersion{3, 10, 2}},
+		{"[warning] something\nsops 3.12.1 (latest)\n", sopsVersion{3, 12, 1}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSopsVersion(tt.output)
+		if err != nil {
+			t.Fatalf("parseSopsVersion(%q): %s", tt.output, err)
+		}
+		if got != tt.want {
+			t.Fatalf("parseSopsVersion(%q) = %s, want %s", tt.output, got, tt.want)
+		}
+	}
+
+	if _, err := parseSopsVersion("Usage: sops [options]"); err == nil {
+		t.Fatal("expected an error for output without a version")
+	}
+}
+
+func TestAccProvider_SopsBinaryPathMissing(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+`, filepath.Join(t.TempDir(), "sops"), testAgePublicKey),
+				ExpectError: regexp.MustCompile(`(?s)Unusable SOPS Binary.*sops\s+binary\s+not\s+found`),
+			},
+		},
+	})
+}
+
+func TestAccProvider_SopsBinaryTooOld(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+`, testFakeSopsBinary(t, "3.8.1", ""), testAgePublicKey),
+				ExpectError: regexp.MustCompile(`is\s+sops\s+3\.8\.1,\s+but\s+the\s+provider\s+requires\s+sops\s+3\.9\.0\s+or\s+later`),
+			},
+		},
+	})
+}
+
+func TestAccEncrypt_SopsFeatureGatedHybridRecipient(t *testing.T) {
+	_, publicKey, err := generateAgeKeyPair(ageKeyTypeMLKEM768X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+`, testFakeSopsBinary(t, "3.11.0", ""), publicKey),
+				ExpectError: regexp.MustCompile(`post-quantum\s+age\s+keys\s+require\s+sops\s+3\.12\.0\s+or\s+later`),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_SopsFeatureGatedSSHKey(t *testing.T) {
+	keyPath, _ := testSSHKeyPair(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path         = %q
+  age_ssh_private_key_path = %q
+}
+
+data "sops_decrypt" "test" {
+  input      = "{}"
+  input_type = "json"
+}
+`, testFakeSopsBinary(t, "3.9.0", ""), keyPath),
+				ExpectError: regexp.MustCompile(`age\s+SSH\s+keys\s+require\s+sops\s+3\.10\.0\s+or\s+later`),
+			},
+		},
+	})
+}
+
+func TestAccProvider_SopsBinarySHA256(t *testing.T) {
+	sops := testFakeSopsBinary(t, "3.12.0", "")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path   = %q
+  sops_binary_sha256 = %q
+}
+
+data "sops_age_public_key" "test" {
+  private_key = %q
+}
+`, sops, testFileSHA256(t, sops), testAgeSecretKey),
+				Check: resource.TestCheckResourceAttr("data.sops_age_public_key.test", "public_key", testAgePublicKey),
+			},
+		},
+	})
+}
+
+func TestAccProvider_SopsBinarySHA256Mismatch(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path   = %q
+  sops_binary_sha256 = %q
+}
+
+data "sops_age_public_key" "test" {
+  private_key = %q
+}
+`, testFakeSopsBinary(t, "3.12.0", ""), strings.Repeat("0", 64), testAgeSecretKey),
+				ExpectError: regexp.MustCompile(`(?s)refusing\s+to\s+run.*SHA-256\s+digest`),
+			},
+		},
+	})
+}
+
+func TestSopsBinaryReplacedAfterProbe(t *testing.T) {
+	path := testFakeSopsBinary(t, "3.12.0", "")
+	sops, err := probeSopsBinary(t.Context(), path, testFileSHA256(t, path), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sops.run(t.Context(), []string{"--version"}, nil, nil); err != nil {
+		t.Fatalf("unexpected error before replacement: %s", err)
+	}
+
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho 'sops 3.12.0 (evil)'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sops.run(t.Context(), []string{"--version"}, nil, nil); err == nil || !strings.Contains(err.Error(), "was replaced") {
+		t.Fatalf("expected the replaced binary to be refused, got %v", err)
+	}
+}
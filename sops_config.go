@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SopsKeyGroup is a set of master keys that together protect one Shamir
+// share of the data key.
+type SopsKeyGroup struct {
+	AgeRecipients      []string
+	PGPFingerprints    []string
+	HCVaultTransitURIs []string
+	KMSARNs            []string
+	GCPKMSResourceIDs  []string
+	AzureKVURLs        []string
+}
+
+func (g SopsKeyGroup) keyCount() int {
+	return len(g.AgeRecipients) + len(g.PGPFingerprints) + len(g.HCVaultTransitURIs) +
+		len(g.KMSARNs) + len(g.GCPKMSResourceIDs) + len(g.AzureKVURLs)
+}
+
+// The sops* config types mirror the creation rule schema of .sops.yaml.
+// SOPS parses the file as YAML, so rendering it as JSON is sufficient.
+type sopsConfigFile struct {
+	CreationRules []sopsCreationRule `json:"creation_rules"`
+}
+
+type sopsCreationRule struct {
+	ShamirThreshold int64                `json:"shamir_threshold,omitempty"`
+	KeyGroups       []sopsConfigKeyGroup `json:"key_groups"`
+}
+
+type sopsConfigKeyGroup struct {
+	Age     []string             `json:"age,omitempty"`
+	PGP     []string             `json:"pgp,omitempty"`
+	HCVault []string             `json:"hc_vault,omitempty"`
+	KMS     []sopsConfigKMSKey   `json:"kms,omitempty"`
+	GCPKMS  []sopsConfigGCPKey   `json:"gcp_kms,omitempty"`
+	AzureKV []sopsConfigAzureKey `json:"azure_keyvault,omitempty"`
+}
+
+type sopsConfigKMSKey struct {
+	ARN     string            `json:"arn"`
+	Role    string            `json:"role,omitempty"`
+	Context map[string]string `json:"context,omitempty"`
+}
+
+type sopsConfigGCPKey struct {
+	ResourceID string `json:"resource_id"`
+}
+
+type sopsConfigAzureKey struct {
+	VaultURL string `json:"vaultUrl"`
+	Key      string `json:"key"`
+	Version  string `json:"version"`
+}
+
+// splitAzureKVURL splits https://<vault>/keys/<name>[/<version>] into the
+// parts the SOPS config expects.
+func splitAzureKVURL(keyURL string) (sopsConfigAzureKey, error) {
+	parsed, err := url.Parse(keyURL)
+	if err != nil {
+		return sopsConfigAzureKey{}, fmt.Errorf("invalid Azure Key Vault key URL %q: %w", keyURL, err)
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "keys" {
+		return sopsConfigAzureKey{}, fmt.Errorf("invalid Azure Key Vault key URL %q: expected https://<vault>/keys/<name>[/<version>]", keyURL)
+	}
+
+	key := sopsConfigAzureKey{
+		VaultURL: parsed.Scheme + "://" + parsed.Host,
+		Key:      parts[1],
+	}
+	if len(parts) == 3 {
+		key.Version = parts[2]
+	}
+	return key, nil
+}
+
+func buildKeyGroupsConfig(opts SopsEncryptOptions) (sopsConfigFile, error) {
+	rule := sopsCreationRule{}
+	if opts.ShamirThreshold != nil {
+		rule.ShamirThreshold = *opts.ShamirThreshold
+	}
+
+	for _, group := range opts.KeyGroups {
+		configGroup := sopsConfigKeyGroup{
+			Age:     group.AgeRecipients,
+			PGP:     group.PGPFingerprints,
+			HCVault: group.HCVaultTransitURIs,
+		}
+
+		for _, arn := range group.KMSARNs {
+			configGroup.KMS = append(configGroup.KMS, sopsConfigKMSKey{
+				ARN:     arn,
+				Role:    opts.KMSRole,
+				Context: opts.KMSEncryptionContext,
+			})
+		}
+
+		for _, resourceID := range group.GCPKMSResourceIDs {
+			configGroup.GCPKMS = append(configGroup.GCPKMS, sopsConfigGCPKey{ResourceID: resourceID})
+		}
+
+		for _, keyURL := range group.AzureKVURLs {
+			key, err := splitAzureKVURL(keyURL)
+			if err != nil {
+				return sopsConfigFile{}, err
+			}
+			configGroup.AzureKV = append(configGroup.AzureKV, key)
+		}
+
+		rule.KeyGroups = append(rule.KeyGroups, configGroup)
+	}
+
+	return sopsConfigFile{CreationRules: []sopsCreationRule{rule}}, nil
+}
+
+// writeKeyGroupsConfig renders the key groups as a temporary SOPS config
+// file, since the sops command line cannot express key groups. The caller
+// must remove the returned file.
+func writeKeyGroupsConfig(opts SopsEncryptOptions) (string, error) {
+	config, err := buildKeyGroupsConfig(opts)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal key groups config: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "sops-key-groups-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to create key groups config: %w", err)
+	}
+
+	if _, err := file.Write(content); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write key groups config: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write key groups config: %w", err)
+	}
+
+	return file.Name(), nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testSopsConfigFile(t *testing.T) string {
+	t.Helper()
+
+	config := fmt.Sprintf(`creation_rules:
+  - path_regex: ^secrets/.*\.yaml$
+    encrypted_regex: ^password$
+    age: %s
+  - path_regex: ^team-b/
+    age: %s
+`, testAgePublicKey, testAgePublicKey2)
+
+	path := filepath.Join(t.TempDir(), ".sops.yaml")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAccEncryptResource_CreationRule(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccEncryptResourcePreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  config_path        = %q
+  age_identity_value = %q
+}
+
+resource "sops_encrypt" "app" {
+  input = {
+    username = "admin"
+    password = "hunter2"
+  }
+  filename = "secrets/app.yaml"
+}
+
+resource "sops_encrypt" "team_b" {
+  input = {
+    password = "team-b"
+  }
+  filename = "team-b/app.yaml"
+}
+
+data "sops_decrypt" "app" {
+  input      = sops_encrypt.app.output
+  input_type = "json"
+}
+`, testSopsConfigFile(t), testAgeSecretKey),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckEncryptedOutputUsesCorrectAge("sops_encrypt.app", testAgePublicKey),
+					testAccCheckFieldIsEncrypted("sops_encrypt.app", "password"),
+					resource.TestCheckResourceAttrWith("sops_encrypt.app", "output", func(output string) error {
+						if !strings.Contains(output, `"username": "admin"`) {
+							return fmt.Errorf("expected username to be left unencrypted by the creation rule")
+						}
+						return nil
+					}),
+					testAccCheckEncryptedOutputUsesCorrectAge("sops_encrypt.team_b", testAgePublicKey2),
+					resource.TestCheckResourceAttr("data.sops_decrypt.app", "output.password", "hunter2"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_CreationRuleExplicitRecipients(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// Explicit recipients replace the rule's keys, but the rule's
+				// encrypted_regex still applies.
+				Config: fmt.Sprintf(`
+provider "sops" {
+  config_path = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    username = "admin"
+    password = "hunter2"
+  }
+  age_recipients = [%q]
+  filename       = "secrets/app.yaml"
+}
+`, testSopsConfigFile(t), testAgePublicKey2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckEncryptedOutputUsesCorrectAge("data.sops_encrypt.test", testAgePublicKey2),
+					testAccCheckFieldIsEncrypted("data.sops_encrypt.test", "password"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_FilenameWithoutConfigPath(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "sops_encrypt" "test" {
+  input = {
+    password = "hunter2"
+  }
+  filename = "secrets/app.yaml"
+}
+`,
+				ExpectError: regexp.MustCompile(`requires\s+config_path\s+to\s+be\s+set\s+on\s+the\s+provider`),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_FilenameWithKeyGroup(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "sops_encrypt" "test" {
+  input = {
+    password = "hunter2"
+  }
+  filename = "secrets/app.yaml"
+
+  key_group {
+    age_recipients = [%q]
+  }
+}
+`, testAgePublicKey),
+				ExpectError: regexp.MustCompile(`key_group\s+blocks\s+cannot\s+be\s+combined\s+with\s+filename`),
+			},
+		},
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccSopsEnvironmentConfig(providerConfig string) string {
+	return fmt.Sprintf(`
+provider "sops" {
+%s
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "environment-value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, providerConfig, testAgePublicKey)
+}
+
+func TestAccDecrypt_InheritEnvironment(t *testing.T) {
+	// An ambient identity only reaches sops when the environment is inherited.
+	t.Setenv("SOPS_AGE_KEY", testAgeSecretKey)
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccSopsEnvironmentConfig(""),
+				Check:  resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "environment-value"),
+			},
+			{
+				Config:      testAccSopsEnvironmentConfig("  inherit_environment = false"),
+				ExpectError: regexp.MustCompile("SOPS Decryption Failed"),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_Environment(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", testAgeSecretKey2)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccSopsEnvironmentConfig(fmt.Sprintf(`
+  inherit_environment = false
+  environment = {
+    SOPS_AGE_KEY = %q
+  }`, testAgeSecretKey)),
+				Check: resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "environment-value"),
+			},
+		},
+	})
+}
+
+func TestAccProvider_InvalidEnvironmentName(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccSopsEnvironmentConfig(`  environment = { "NOT-A-NAME" = "value" }`),
+				ExpectError: regexp.MustCompile("must be an environment variable name"),
+			},
+		},
+	})
+}
+
+func TestSopsBackendOptionsBaseEnviron(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("SOPS_AGE_KEY", testAgeSecretKey)
+
+	opts := SopsBackendOptions{
+		IsolateEnvironment: true,
+		Environment:        map[string]string{"AWS_PROFILE": "ci"},
+	}
+	env := opts.baseEnviron()
+
+	if !slices.Contains(env, "PATH=/usr/bin") || !slices.Contains(env, "AWS_PROFILE=ci") {
+		t.Fatalf("expected PATH and the environment map, got %v", environmentNames(env))
+	}
+	if slices.Contains(environmentNames(env), "SOPS_AGE_KEY") {
+		t.Fatal("ambient SOPS_AGE_KEY leaked into an isolated environment")
+	}
+}
+
+func TestAccProvider_SopsVersionProbeEnvironment(t *testing.T) {
+	// The fake reports whatever version its environment asks for.
+	t.Setenv("FAKE_SOPS_VERSION", "3.8.0")
+	sops := testFakeSopsBinary(t, "${FAKE_SOPS_VERSION}", "")
+
+	config := func(providerConfig string) string {
+		return fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path = %q
+%s
+}
+
+data "sops_age_public_key" "test" {
+  private_key = %q
+}
+`, sops, providerConfig, testAgeSecretKey)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config(""),
+				ExpectError: regexp.MustCompile(`is\s+sops\s+3\.8\.0`),
+			},
+			{
+				Config: config(`
+  inherit_environment = false
+  environment = {
+    FAKE_SOPS_VERSION = "3.12.0"
+  }`),
+				Check: resource.TestCheckResourceAttr("data.sops_age_public_key.test", "public_key", testAgePublicKey),
+			},
+		},
+	})
+}
+
+func TestSopsBackendOptionsSecrets(t *testing.T) {
+	opts := SopsBackendOptions{
+		Environment: map[string]string{
+			"AWS_SECRET_ACCESS_KEY": "wJalrXUtnFEMI",
+			"VAULT_TOKEN":           "hvs.token",
+			"HOME":                  "/home/ci",
+			"AWS_PROFILE":           "ci-profile",
+		},
+	}
+	secrets := opts.secrets()
+
+	for _, secret := range []string{"wJalrXUtnFEMI", "hvs.token"} {
+		if !slices.Contains(secrets, secret) {
+			t.Errorf("credential %q is not redacted", secret)
+		}
+	}
+	for _, value := range []string{"/home/ci", "ci-profile"} {
+		if slices.Contains(secrets, value) {
+			t.Errorf("ordinary value %q is redacted", value)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// sopsErrorCategory classifies why a sops operation failed.
+type sopsErrorCategory int
+
+const (
+	sopsErrorUnknown sopsErrorCategory = iota
+	sopsErrorBinaryNotFound
+	sopsErrorNoMatchingIdentity
+	sopsErrorMACMismatch
+	sopsErrorMalformedDocument
+	sopsErrorUnsupportedFormat
+)
+
+func (c sopsErrorCategory) String() string {
+	switch c {
+	case sopsErrorBinaryNotFound:
+		return "binary_not_found"
+	case sopsErrorNoMatchingIdentity:
+		return "no_matching_identity"
+	case sopsErrorMACMismatch:
+		return "mac_mismatch"
+	case sopsErrorMalformedDocument:
+		return "malformed_document"
+	case sopsErrorUnsupportedFormat:
+		return "unsupported_format"
+	}
+	return "unknown"
+}
+
+// Exit statuses of the sops binary, from sops' cmd/sops/codes package.
+const (
+	sopsExitCouldNotReadInputFile = 2
+	sopsExitErrorDumpingTree      = 4
+	sopsExitErrorDecryptingMac    = 24
+	sopsExitErrorDecryptingTree   = 25
+	sopsExitMacMismatch           = 51
+	sopsExitMacNotFound           = 52
+	sopsExitCouldNotRetrieveKey   = 128
+	sopsExitFileAlreadyEncrypted  = 203
+)
+
+// sopsError is a failed sops run. Its message keeps the exit status and the
+// redacted stderr of the process, and its category drives the diagnostic
+// shown to the user.
+type sopsError struct {
+	Operation string
+	Category  sopsErrorCategory
+	ExitCode  int
+	Stderr    string
+	Err       error
+}
+
+func newSopsError(operation string, err error, stderr []byte, redact *redactor) *sopsError {
+	e := &sopsError{
+		Operation: operation,
+		ExitCode:  sopsExitCode(err),
+		Err:       err,
+	}
+	// Classify before redacting, since a redacted value may also be a word
+	// of the sops message.
+	e.Category = classifySopsFailure(err, e.ExitCode, string(stderr))
+	e.Stderr = redact.redact(strings.TrimSpace(string(stderr)))
+	return e
+}
+
+// sopsExitCode returns the exit status of the sops process behind err: 0
+// for success, or -1 when sops did not run or was killed.
+func sopsExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func (e *sopsError) Error() string {
+	return fmt.Sprintf("sops %s failed: %s%s", e.Operation, e.Err, formatSopsStderr(e.Stderr))
+}
+
+func (e *sopsError) Unwrap() error {
+	return e.Err
+}
+
+// classifySopsFailure maps a failure to a category by exit status first, and
+// by the wording of sops' stderr for statuses sops shares between failures.
+func classifySopsFailure(err error, exitCode int, stderr string) sopsErrorCategory {
+	if errors.Is(err, exec.ErrNotFound) {
+		return sopsErrorBinaryNotFound
+	}
+
+	switch exitCode {
+	case sopsExitCouldNotRetrieveKey:
+		return sopsErrorNoMatchingIdentity
+	case sopsExitMacMismatch, sopsExitMacNotFound, sopsExitErrorDecryptingMac:
+		return sopsErrorMACMismatch
+	case sopsExitErrorDecryptingTree, sopsExitCouldNotReadInputFile, sopsExitFileAlreadyEncrypted:
+		return sopsErrorMalformedDocument
+	case sopsExitErrorDumpingTree:
+		return sopsErrorUnsupportedFormat
+	}
+
+	switch {
+	case strings.Contains(stderr, "Failed to get the data key"):
+		return sopsErrorNoMatchingIdentity
+	case strings.Contains(stderr, "MAC mismatch"):
+		return sopsErrorMACMismatch
+	case strings.Contains(stderr, "only supports"), strings.Contains(stderr, "cannot use complex value"):
+		return sopsErrorUnsupportedFormat
+	case strings.Contains(stderr, "sops metadata not found"),
+		strings.Contains(stderr, "Could not unmarshal input data"),
+		strings.Contains(stderr, "unmarshal errors"),
+		strings.Contains(stderr, "invalid dotenv input line"):
+		return sopsErrorMalformedDocument
+	}
+	return sopsErrorUnknown
+}
+
+// sopsErrorSummaries extend the "SOPS Decryption Failed" and "SOPS
+// Encryption Failed" summaries for classified failures.
+var sopsErrorSummaries = map[sopsErrorCategory]string{
+	sopsErrorBinaryNotFound:     "SOPS Binary Not Found",
+	sopsErrorNoMatchingIdentity: "No Matching Identity",
+	sopsErrorMACMismatch:        "MAC Mismatch",
+	sopsErrorMalformedDocument:  "Malformed Document",
+	sopsErrorUnsupportedFormat:  "Unsupported Format",
+}
+
+// hint suggests how to resolve a classified failure.
+func (e *sopsError) hint() string {
+	switch e.Category {
+	case sopsErrorBinaryNotFound:
+		return "Install sops 3.9.0 or later on the machine running Terraform, or set sops_binary_path on the provider."
+	case sopsErrorNoMatchingIdentity:
+		if e.Operation == "encrypt" {
+			return "None of the recipients' keys could be used. Check the recipients and the credentials for their key backends."
+		}
+		return "None of the configured identities or key backends could unwrap the data key. Configure an age identity that matches one of the document's recipients, or credentials for one of its KMS, PGP or Vault keys."
+	case sopsErrorMACMismatch:
+		return "The document was modified after it was encrypted, or its values do not belong to its metadata. Re-encrypt it from the original plaintext."
+	case sopsErrorMalformedDocument:
+		if e.Operation == "encrypt" {
+			return "The input could not be read as a plaintext document. If it already contains a top-level \"sops\" key, it is already encrypted or the key must be renamed."
+		}
+		return "The input is not a complete SOPS-encrypted document. Check that input_type matches the format of the input and that the document was not truncated."
+	case sopsErrorUnsupportedFormat:
+		return "The document's structure cannot be represented in the requested format. Flat formats such as dotenv only hold top-level string values; use json or yaml for nested data."
+	}
+	return ""
+}
+
+// addSopsErrorDiagnostic reports err under summary. Classified sops failures
+// get a more specific summary and a remediation hint. Secrets are redacted
+// from the detail whichever step of the operation failed.
+func addSopsErrorDiagnostic(diags *diag.Diagnostics, summary, detail string, err error) {
+	var sopsErr *sopsError
+	if !errors.As(err, &sopsErr) || sopsErr.Category == sopsErrorUnknown {
+		diags.AddError(summary, redactSecrets(fmt.Sprintf("%s: %s", detail, err)))
+		return
+	}
+
+	diags.AddError(
+		summary+": "+sopsErrorSummaries[sopsErr.Category],
+		redactSecrets(fmt.Sprintf("%s: %s\n\n%s", detail, err, sopsErr.hint())),
+	)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestClassifySopsFailure(t *testing.T) {
+	exitErr := fmt.Errorf("exit status")
+	for name, tc := range map[string]struct {
+		err      error
+		exitCode int
+		stderr   string
+		want     sopsErrorCategory
+	}{
+		"binary_not_found": {err: &exec.Error{Name: "sops", Err: exec.ErrNotFound}, exitCode: -1, want: sopsErrorBinaryNotFound},
+		"deferred_lookup":  {err: fmt.Errorf("sops binary not found: %w", &exec.Error{Name: "sops", Err: exec.ErrNotFound}), exitCode: -1, want: sopsErrorBinaryNotFound},
+		"no_identity":      {err: exitErr, exitCode: 128, stderr: "Failed to get the data key required to decrypt the SOPS file.", want: sopsErrorNoMatchingIdentity},
+		"mac_mismatch":     {err: exitErr, exitCode: 51, stderr: "MAC mismatch. File has 1234, computed 5678", want: sopsErrorMACMismatch},
+		"tampered_value":   {err: exitErr, exitCode: 25, stderr: "Error decrypting tree: Error walking tree", want: sopsErrorMalformedDocument},
+		"not_encrypted":    {err: exitErr, exitCode: 1, stderr: "sops metadata not found", want: sopsErrorMalformedDocument},
+		"wrong_input_type": {err: exitErr, exitCode: 1, stderr: "Could not unmarshal input data: invalid character 'a'", want: sopsErrorMalformedDocument},
+		"nested_dotenv":    {err: exitErr, exitCode: 4, stderr: "Could not marshal tree: cannot use complex value in dotenv file; offending key a", want: sopsErrorUnsupportedFormat},
+		"top_level_array":  {err: exitErr, exitCode: 1, stderr: "Could not unmarshal input data: SOPS only supports JSON files with a top-level object", want: sopsErrorUnsupportedFormat},
+		"unknown":          {err: exitErr, exitCode: 1, stderr: "failed to parse input as Bech32-encoded age public key", want: sopsErrorUnknown},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := classifySopsFailure(tc.err, tc.exitCode, tc.stderr); got != tc.want {
+				t.Fatalf("got category %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccDecrypt_NoMatchingIdentityDiagnostic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+
+data "sops_decrypt" "test" {
+  input      = data.sops_encrypt.test.output
+  input_type = "json"
+}
+`, testAgeSecretKey2, testAgePublicKey),
+				ExpectError: regexp.MustCompile(`(?s)SOPS Decryption Failed: No Matching Identity.*Configure\s+an\s+age\s+identity`),
+			},
+		},
+	})
+}
+
+func TestAccDecrypt_MalformedDocumentDiagnostic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_decrypt" "test" {
+  input      = jsonencode({ secret = "not encrypted" })
+  input_type = "json"
+}
+`, testAgeSecretKey),
+				ExpectError: regexp.MustCompile(`(?s)SOPS Decryption Failed: Malformed Document.*sops\s+metadata\s+not\s+found`),
+			},
+		},
+	})
+}
+
+func TestAccDecryptEphemeralResource_MACMismatchDiagnostic(t *testing.T) {
+	encrypted := encryptFixture(t)
+	tampered := regexp.MustCompile(`"lastmodified": "[^"]*"`).ReplaceAllString(encrypted, `"lastmodified": "2000-01-01T00:00:00Z"`)
+
+	resource.Test(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		PreCheck:                 func() { testAccDecryptEphemeralPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+ephemeral "sops_decrypt" "test" {
+  input      = %q
+  input_type = "json"
+}
+`, testAgeSecretKey, tampered),
+				ExpectError: regexp.MustCompile(`(?s)SOPS Decryption Failed: MAC Mismatch.*Re-encrypt\s+it`),
+			},
+		},
+	})
+}
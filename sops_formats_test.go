@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccEncryptDecrypt_Dotenv(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+resource "sops_encrypt" "env" {
+  input = {
+    DATABASE_URL = "postgres://app:hunter2@db/app"
+    PORT         = "8080"
+  }
+  age_recipients = [%q]
+  output_type    = "dotenv"
+}
+
+data "sops_decrypt" "env" {
+  input      = sops_encrypt.env.output
+  input_type = "dotenv"
+}
+`, testAgeSecretKey, testAgePublicKey),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("sops_encrypt.env", "output", regexp.MustCompile(`(?m)^DATABASE_URL=ENC\[`)),
+					resource.TestMatchResourceAttr("sops_encrypt.env", "output", regexp.MustCompile(`(?m)^sops_version=`)),
+					resource.TestCheckResourceAttr("data.sops_decrypt.env", "output.DATABASE_URL", "postgres://app:hunter2@db/app"),
+					resource.TestCheckResourceAttr("data.sops_decrypt.env", "output.PORT", "8080"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDecrypt_INI(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  age_identity_value = %q
+}
+
+data "sops_encrypt" "ini" {
+  input = {
+    database = {
+      user     = "app"
+      password = "hunter2"
+    }
+  }
+  age_recipients = [%q]
+  output_type    = "ini"
+}
+
+data "sops_decrypt" "ini" {
+  input      = data.sops_encrypt.ini.output
+  input_type = "ini"
+}
+`, testAgeSecretKey, testAgePublicKey),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("data.sops_encrypt.ini", "output", regexp.MustCompile(`(?m)^\[database\]$`)),
+					resource.TestCheckResourceAttr("data.sops_decrypt.ini", "output.database.password", "hunter2"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncrypt_OutputShapeValidation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		outputType string
+		input      string
+		want       string
+	}{
+		"dotenv_nested": {
+			outputType: "dotenv",
+			input:      `{ database = { password = "hunter2" } }`,
+			want:       `dotenv\s+output\s+requires\s+a\s+flat\s+map\s+of\s+strings,\s+but\s+"database"\s+is\s+a\s+map`,
+		},
+		"dotenv_number": {
+			outputType: "dotenv",
+			input:      `{ PORT = 8080 }`,
+			want:       `"PORT"\s+is\s+a\s+number`,
+		},
+		"ini_top_level_value": {
+			outputType: "ini",
+			input:      `{ password = "hunter2" }`,
+			want:       `ini\s+output\s+requires\s+a\s+map\s+of\s+sections,\s+but\s+"password"\s+is\s+a\s+string`,
+		},
+		"ini_nested_section": {
+			outputType: "ini",
+			input:      `{ database = { primary = { password = "hunter2" } } }`,
+			want:       `"primary"\s+in\s+section\s+"database"\s+is\s+a\s+map`,
+		},
+		"unknown_format": {
+			outputType: "toml",
+			input:      `{ password = "hunter2" }`,
+			want:       `value\s+must\s+be\s+one\s+of`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				PreCheck:                 func() { testAccPreCheck(t) },
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+resource "sops_encrypt" "test" {
+  input          = %s
+  age_recipients = [%q]
+  output_type    = %q
+}
+`, tc.input, testAgePublicKey, tc.outputType),
+						ExpectError: regexp.MustCompile(tc.want),
+					},
+				},
+			})
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// sopsLogSubsystem traces encryptions and decryptions. Its level follows
+// TF_LOG_PROVIDER, or TF_LOG_PROVIDER_SOPS_SOPS when set.
+const sopsLogSubsystem = "sops"
+
+// newSopsLogContext returns ctx with the sops subsystem logger, masking the
+// secrets known to redact.
+func newSopsLogContext(ctx context.Context, redact *redactor) context.Context {
+	ctx = tflog.NewSubsystem(ctx, sopsLogSubsystem,
+		tflog.WithLevelFromEnv("TF_LOG_PROVIDER_SOPS_SOPS"),
+		tflog.WithRootFields(),
+	)
+	return redact.logContext(ctx)
+}
+
+// sopsOperationLog traces one sops operation. Its fields only describe the
+// operation, such as formats, sizes and counts, and never hold its data.
+type sopsOperationLog struct {
+	fields map[string]interface{}
+	start  time.Time
+}
+
+func startSopsOperationLog(ctx context.Context, fields map[string]interface{}) *sopsOperationLog {
+	tflog.SubsystemDebug(ctx, sopsLogSubsystem, "Starting sops operation", fields)
+	return &sopsOperationLog{fields: fields, start: time.Now()}
+}
+
+// finish logs the duration and outcome of the operation, given the error
+// returned to the caller.
+func (l *sopsOperationLog) finish(ctx context.Context, output []byte, err error) {
+	fields := make(map[string]interface{}, len(l.fields)+4)
+	for key, value := range l.fields {
+		fields[key] = value
+	}
+	fields["duration_ms"] = time.Since(l.start).Milliseconds()
+	fields["exit_code"] = sopsExitCode(err)
+
+	if err != nil {
+		category := sopsErrorUnknown
+		var sopsErr *sopsError
+		if errors.As(err, &sopsErr) {
+			category = sopsErr.Category
+		}
+		fields["error_category"] = category.String()
+		tflog.SubsystemError(ctx, sopsLogSubsystem, "Sops operation failed", fields)
+		return
+	}
+	fields["output_bytes"] = len(output)
+	tflog.SubsystemDebug(ctx, sopsLogSubsystem, "Finished sops operation", fields)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestSopsOperationLogging(t *testing.T) {
+	sops, err := probeSopsBinary(t.Context(), testFakeSopsBinary(t, "3.12.0", ""), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	_, err = encryptWithSops(ctx, map[string]interface{}{"password": "correct-horse-battery"}, SopsEncryptOptions{
+		AgeRecipients: []string{testAgePublicKey},
+		OutputType:    "yaml",
+		Backend:       SopsBackendOptions{Sops: sops},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var finished map[string]interface{}
+	for _, entry := range entries {
+		if entry["@module"] == "provider.sops" && entry["@message"] == "Finished sops operation" {
+			finished = entry
+		}
+	}
+	if finished == nil {
+		t.Fatalf("no finished operation logged in %v", entries)
+	}
+	for key, want := range map[string]interface{}{
+		"operation":       "encrypt",
+		"output_type":     "yaml",
+		"recipient_count": float64(1),
+		"exit_code":       float64(0),
+	} {
+		if finished[key] != want {
+			t.Errorf("%s = %v, want %v", key, finished[key], want)
+		}
+	}
+	for _, key := range []string{"input_bytes", "output_bytes", "duration_ms"} {
+		if _, ok := finished[key]; !ok {
+			t.Errorf("missing %s", key)
+		}
+	}
+
+	if strings.Contains(output.String(), "correct-horse-battery") {
+		t.Fatal("log leaks plaintext")
+	}
+}
+
+func TestSopsOperationLoggingFailure(t *testing.T) {
+	sops, err := probeSopsBinary(t.Context(), testFakeSopsBinary(t, "3.12.0", "echo 'Failed to get the data key required to decrypt the SOPS file.' >&2\nexit 128"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	_, err = decryptWithSops(ctx, []byte("{}"), SopsDecryptOptions{
+		InputType:        "json",
+		AgeIdentityValue: testAgeSecretKey,
+		Backend:          SopsBackendOptions{Sops: sops},
+	})
+	if err == nil {
+		t.Fatal("expected sops to fail")
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed map[string]interface{}
+	for _, entry := range entries {
+		if entry["@message"] == "Sops operation failed" {
+			failed = entry
+		}
+	}
+	if failed == nil {
+		t.Fatalf("no failed operation logged in %v", entries)
+	}
+	if failed["exit_code"] != float64(128) || failed["error_category"] != "no_matching_identity" {
+		t.Errorf("unexpected outcome fields: exit_code=%v error_category=%v", failed["exit_code"], failed["error_category"])
+	}
+	if strings.Contains(output.String(), testAgeSecretKey) {
+		t.Fatal("log leaks the age identity")
+	}
+}
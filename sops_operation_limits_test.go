@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccEncrypt_OperationTimeout(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  sops_binary_path  = %q
+  operation_timeout = "1s"
+}
+
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  age_recipients = [%q]
+}
+`, testFakeSopsBinary(t, "3.12.0", "exec sleep 60"), testAgePublicKey),
+				ExpectError: regexp.MustCompile(`timed\s+out\s+after\s+1s`),
+			},
+		},
+	})
+}
+
+func TestAccProvider_InvalidOperationTimeout(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  operation_timeout = "soon"
+}
+
+data "sops_age_public_key" "test" {
+  private_key = %q
+}
+`, testAgeSecretKey),
+				ExpectError: regexp.MustCompile("Invalid Duration"),
+			},
+		},
+	})
+}
+
+func TestSopsBinaryOperationTimeout(t *testing.T) {
+	sops, err := probeSopsBinary(t.Context(), testFakeSopsBinary(t, "3.12.0", "exec sleep 60"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sops.Timeout = 200 * time.Millisecond
+
+	start := time.Now()
+	_, _, err = sops.run(t.Context(), []string{"decrypt"}, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("timed out operation took %s", elapsed)
+	}
+}
+
+func TestSopsBinaryMaxConcurrent(t *testing.T) {
+	sops, err := probeSopsBinary(t.Context(), testFakeSopsBinary(t, "3.12.0", ""), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sops.setMaxConcurrent(1)
+
+	// Occupy the only slot, as a running operation would.
+	sops.slots <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := sops.run(ctx, []string{"--version"}, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to wait for a free slot, got %v", err)
+	}
+
+	<-sops.slots
+	if _, _, err := sops.run(t.Context(), []string{"--version"}, nil, nil); err != nil {
+		t.Fatalf("unexpected error with a free slot: %s", err)
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
+	"unicode/utf8"
 
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -45,6 +47,34 @@ func convertDynamicValueToBytes(dyn types.Dynamic) ([]byte, error) {
 	return marshalDynamicValue(dyn)
 }
 
+// binaryInput returns the payload of a binary encryption, decoded from
+// inputBase64 or taken from input as plain text.
+func binaryInput(input types.Dynamic, inputBase64 types.String) ([]byte, error) {
+	if !inputBase64.IsNull() {
+		payload, err := base64.StdEncoding.DecodeString(inputBase64.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("input_base64 is not valid base64: %w", err)
+		}
+		return payload, nil
+	}
+
+	text, ok := input.UnderlyingValue().(types.String)
+	if !ok || text.IsNull() || text.IsUnknown() {
+		return nil, fmt.Errorf("binary input must be a known string, got %T", input.UnderlyingValue())
+	}
+	return []byte(text.ValueString()), nil
+}
+
+// binaryOutputValues returns a decrypted binary payload as text, when it is
+// valid UTF-8, and as base64.
+func binaryOutputValues(payload []byte) (types.Dynamic, types.String) {
+	text := types.DynamicNull()
+	if utf8.Valid(payload) {
+		text = types.DynamicValue(types.StringValue(string(payload)))
+	}
+	return text, types.StringValue(base64.StdEncoding.EncodeToString(payload))
+}
+
 func convertAttrValueToGo(val attr.Value) (interface{}, error) {
 	if val.IsNull() {
 		return nil, nil
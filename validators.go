@@ -2,19 +2,105 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"regexp"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type ageRecipientValidator struct{}
+
+func (v ageRecipientValidator) Description(ctx context.Context) string {
+	return "value must be an age public key in age1... or age1pq1... format, an age plugin recipient, or an ssh-ed25519 or ssh-rsa public key"
+}
+
+func (v ageRecipientValidator) MarkdownDescription(ctx context.Context) string {
+	return "value must be an age public key in `age1...` or `age1pq1...` format, an age plugin recipient, or an `ssh-ed25519` or `ssh-rsa` public key"
+}
+
+func (v ageRecipientValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	// Empty strings are reported by the length validator.
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() || req.ConfigValue.ValueString() == "" {
+		return
+	}
+
+	if err := validateAgeRecipient(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Age Recipient",
+			fmt.Sprintf("Failed to parse recipient: %s", err),
+		)
+	}
+}
+
+// vaultTransitURIValidator mirrors the URI shape SOPS requires to locate the
+// Transit engine mount and key name.
+var vaultTransitURIValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^https?://[^/]+/v1/.+/keys/[^/]+$`),
+	"must be a Vault Transit key URI such as https://vault.example.com:8200/v1/transit/keys/my-key",
+)
+
+// keyServiceAddressValidator accepts the address schemes sops keyservice
+// clients can dial.
+var keyServiceAddressValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^(tcp://[^/\s]+|unix://.+)$`),
+	"must be a keyservice address such as tcp://localhost:5000 or unix:///tmp/sops.sock",
+)
+
+var sha256DigestValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^[0-9a-fA-F]{64}$`),
+	"must be a hex-encoded SHA-256 digest",
+)
+
+var environmentVariableNameValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`),
+	"must be an environment variable name",
+)
+
+var kmsARNValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:\d{12}:(key|alias)/.+$`),
+	"must be an AWS KMS key or alias ARN",
+)
+
+var gcpKMSResourceIDValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`),
+	"must be a GCP KMS crypto key resource ID such as projects/my-project/locations/global/keyRings/my-ring/cryptoKeys/my-key",
+)
+
+var azureKVURLValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^https://[^/]+/keys/[^/]+(/[^/]+)?$`),
+	"must be an Azure Key Vault key URL such as https://my-vault.vault.azure.net/keys/my-key/version",
+)
+
+// SOPS passes the encryption context as comma-separated key:value pairs, so
+// neither separator can appear inside a key or value.
+var encryptionContextEntryValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^[^:,]+$`),
+	"must not be empty or contain ':' or ','",
+)
+
+var iamRoleARNValidator = stringvalidator.RegexMatches(
+	regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`),
+	"must be an AWS IAM role ARN",
 )
 
 type dynamicObjectValidator struct{}
 
 func (v dynamicObjectValidator) Description(ctx context.Context) string {
-	return "value must be an object/map"
+	return "value must be an object/map, or a string when input_type is binary"
 }
 
 func (v dynamicObjectValidator) MarkdownDescription(ctx context.Context) string {
-	return "value must be an object/map"
+	return "value must be an object/map, or a string when `input_type` is `binary`"
 }
 
 func (v dynamicObjectValidator) ValidateDynamic(ctx context.Context, req validator.DynamicRequest, resp *validator.DynamicResponse) {
@@ -26,6 +112,12 @@ func (v dynamicObjectValidator) ValidateDynamic(ctx context.Context, req validat
 		return
 	}
 
+	var inputType types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("input_type"), &inputType)...)
+	if resp.Diagnostics.HasError() || inputType.IsUnknown() {
+		return
+	}
+
 	inputValue, err := convertDynamicValueToGo(req.ConfigValue)
 	if err != nil {
 		resp.Diagnostics.AddAttributeError(
@@ -36,6 +128,18 @@ func (v dynamicObjectValidator) ValidateDynamic(ctx context.Context, req validat
 		return
 	}
 
+	// Binary input is an opaque payload, given as plain text.
+	if inputType.ValueString() == "binary" {
+		if _, ok := inputValue.(string); !ok {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Invalid Input Type",
+				fmt.Sprintf("Input must be a string when input_type is \"binary\", got %T. Use input_base64 for payloads that are not text.", inputValue),
+			)
+		}
+		return
+	}
+
 	if _, ok := inputValue.(map[string]interface{}); !ok {
 		resp.Diagnostics.AddAttributeError(
 			req.Path,
@@ -49,11 +153,11 @@ func (v dynamicObjectValidator) ValidateDynamic(ctx context.Context, req validat
 type ageIdentityValidator struct{}
 
 func (v ageIdentityValidator) Description(ctx context.Context) string {
-	return "value must be an age private key in AGE-SECRET-KEY-1... format"
+	return "value must be an age private key in AGE-SECRET-KEY-1..., AGE-SECRET-KEY-PQ-1... or AGE-PLUGIN-... format"
 }
 
 func (v ageIdentityValidator) MarkdownDescription(ctx context.Context) string {
-	return "value must be an age private key in `AGE-SECRET-KEY-1...` format"
+	return "value must be an age private key in `AGE-SECRET-KEY-1...`, `AGE-SECRET-KEY-PQ-1...` or `AGE-PLUGIN-...` format"
 }
 
 func (v ageIdentityValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
@@ -61,11 +165,307 @@ func (v ageIdentityValidator) ValidateString(ctx context.Context, req validator.
 		return
 	}
 
-	if _, err := deriveAgePublicKey(req.ConfigValue.ValueString()); err != nil {
+	if _, err := parseAgeIdentities(req.ConfigValue.ValueString()); err != nil {
 		resp.Diagnostics.AddAttributeError(
 			req.Path,
 			"Invalid Age Private Key",
-			fmt.Sprintf("Failed to derive public key: %s", err),
+			fmt.Sprintf("Failed to parse age identity: %s", err),
+		)
+	}
+}
+
+// durationValidator accepts Go duration strings such as "90s" or "5m".
+type durationValidator struct{}
+
+func (v durationValidator) Description(ctx context.Context) string {
+	return "value must be a positive duration such as 90s or 5m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return "value must be a positive duration such as `90s` or `5m`"
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	duration, err := time.ParseDuration(req.ConfigValue.ValueString())
+	if err == nil && duration <= 0 {
+		err = fmt.Errorf("must be greater than zero")
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Duration",
+			fmt.Sprintf("Failed to parse duration: %s", err),
+		)
+	}
+}
+
+var encryptRecipientAttributes = []string{
+	"age_recipients",
+	"pgp_fingerprints",
+	"hc_vault_transit_uris",
+	"kms_arns",
+	"gcp_kms_resource_ids",
+	"azure_kv_urls",
+}
+
+// encryptRecipientsValidator checks that sops_encrypt takes its recipients
+// either from the top-level attributes or from key_group blocks. Key group
+// blocks are never null, so the stock attribute combination validators
+// cannot express this.
+type encryptRecipientsValidator struct{}
+
+var _ resource.ConfigValidator = encryptRecipientsValidator{}
+var _ datasource.ConfigValidator = encryptRecipientsValidator{}
+
+func (v encryptRecipientsValidator) Description(ctx context.Context) string {
+	return "recipients must be set either as top-level attributes or as key_group blocks, unless a filename selects a creation rule"
+}
+
+func (v encryptRecipientsValidator) MarkdownDescription(ctx context.Context) string {
+	return "recipients must be set either as top-level attributes or as `key_group` blocks, unless a `filename` selects a creation rule"
+}
+
+func (v encryptRecipientsValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	resp.Diagnostics.Append(v.validate(ctx, req.Config)...)
+}
+
+func (v encryptRecipientsValidator) ValidateDataSource(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	resp.Diagnostics.Append(v.validate(ctx, req.Config)...)
+}
+
+func (v encryptRecipientsValidator) validate(ctx context.Context, config tfsdk.Config) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	topLevelLists := make([]types.List, len(encryptRecipientAttributes))
+	for i, name := range encryptRecipientAttributes {
+		diags.Append(config.GetAttribute(ctx, path.Root(name), &topLevelLists[i])...)
+	}
+
+	var keyGroups types.List
+	diags.Append(config.GetAttribute(ctx, path.Root("key_group"), &keyGroups)...)
+
+	var shamirThreshold types.Int64
+	diags.Append(config.GetAttribute(ctx, path.Root("shamir_threshold"), &shamirThreshold)...)
+
+	var kmsRole types.String
+	diags.Append(config.GetAttribute(ctx, path.Root("kms_role"), &kmsRole)...)
+
+	var kmsContext types.Map
+	diags.Append(config.GetAttribute(ctx, path.Root("kms_encryption_context"), &kmsContext)...)
+
+	var filename types.String
+	diags.Append(config.GetAttribute(ctx, path.Root("filename"), &filename)...)
+
+	if diags.HasError() || keyGroups.IsUnknown() {
+		return diags
+	}
+
+	topLevel := EncryptKeyGroupModel{
+		Age:            topLevelLists[0],
+		PGP:            topLevelLists[1],
+		HCVaultTransit: topLevelLists[2],
+		KMS:            topLevelLists[3],
+		GCPKMS:         topLevelLists[4],
+		AzureKV:        topLevelLists[5],
+	}
+
+	var groups []EncryptKeyGroupModel
+	diags.Append(keyGroups.ElementsAs(ctx, &groups, false)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	switch {
+	case len(groups) > 0 && !topLevel.isEmpty():
+		diags.AddError(
+			"Invalid Attribute Combination",
+			fmt.Sprintf("key_group blocks cannot be combined with the top-level %v attributes.", encryptRecipientAttributes),
+		)
+	case len(groups) > 0 && !filename.IsNull():
+		diags.AddAttributeError(
+			path.Root("filename"),
+			"Invalid Attribute Combination",
+			"key_group blocks cannot be combined with filename, which takes the key groups from a creation rule.",
+		)
+	// With a filename, the recipients can come from the matching creation rule.
+	case len(groups) == 0 && topLevel.isEmpty() && filename.IsNull():
+		diags.AddError(
+			"Invalid Attribute Combination",
+			fmt.Sprintf("At least one of the %v attributes, a key_group block or filename must be specified.", encryptRecipientAttributes),
+		)
+	}
+
+	for i, group := range groups {
+		if group.isEmpty() {
+			diags.AddAttributeError(
+				path.Root("key_group").AtListIndex(i),
+				"Empty Key Group",
+				"Each key_group block must specify at least one recipient.",
+			)
+		}
+	}
+
+	if !shamirThreshold.IsNull() && !shamirThreshold.IsUnknown() {
+		if len(groups) == 0 {
+			diags.AddAttributeError(
+				path.Root("shamir_threshold"),
+				"Invalid Attribute Combination",
+				"shamir_threshold requires at least one key_group block.",
+			)
+		} else if shamirThreshold.ValueInt64() > int64(len(groups)) {
+			diags.AddAttributeError(
+				path.Root("shamir_threshold"),
+				"Invalid Shamir Threshold",
+				fmt.Sprintf("shamir_threshold (%d) cannot exceed the number of key_group blocks (%d).", shamirThreshold.ValueInt64(), len(groups)),
+			)
+		}
+	}
+
+	hasKMS := !topLevel.KMS.IsNull()
+	for _, group := range groups {
+		hasKMS = hasKMS || !group.KMS.IsNull()
+	}
+	if !hasKMS {
+		for name, value := range map[string]bool{
+			"kms_role":               !kmsRole.IsNull(),
+			"kms_encryption_context": !kmsContext.IsNull(),
+		} {
+			if value {
+				diags.AddAttributeError(
+					path.Root(name),
+					"Invalid Attribute Combination",
+					fmt.Sprintf("Attribute \"kms_arns\" must be specified when %q is specified, either at the top level or in a key_group block.", name),
+				)
+			}
+		}
+	}
+
+	return diags
+}
+
+// encryptInputValidator checks at plan time that sops_encrypt has exactly
+// one of input and input_base64, matching input_type, and that the input can
+// be written in the flat formats chosen by output_type.
+type encryptInputValidator struct{}
+
+var _ resource.ConfigValidator = encryptInputValidator{}
+var _ datasource.ConfigValidator = encryptInputValidator{}
+
+func (v encryptInputValidator) Description(ctx context.Context) string {
+	return "exactly one of input and input_base64 must be set, and input must be a flat map of strings for dotenv output, or a map of sections holding strings for ini output"
+}
+
+func (v encryptInputValidator) MarkdownDescription(ctx context.Context) string {
+	return "exactly one of `input` and `input_base64` must be set, and `input` must be a flat map of strings for `dotenv` output, or a map of sections holding strings for `ini` output"
+}
+
+func (v encryptInputValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	resp.Diagnostics.Append(v.validate(ctx, req.Config)...)
+}
+
+func (v encryptInputValidator) ValidateDataSource(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	resp.Diagnostics.Append(v.validate(ctx, req.Config)...)
+}
+
+func (v encryptInputValidator) validate(ctx context.Context, config tfsdk.Config) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var input types.Dynamic
+	diags.Append(config.GetAttribute(ctx, path.Root("input"), &input)...)
+
+	var inputBase64 types.String
+	diags.Append(config.GetAttribute(ctx, path.Root("input_base64"), &inputBase64)...)
+
+	var inputType types.String
+	diags.Append(config.GetAttribute(ctx, path.Root("input_type"), &inputType)...)
+
+	var outputType types.String
+	diags.Append(config.GetAttribute(ctx, path.Root("output_type"), &outputType)...)
+
+	if diags.HasError() || inputType.IsUnknown() {
+		return diags
+	}
+
+	switch {
+	case input.IsNull() == inputBase64.IsNull():
+		diags.AddError(
+			"Invalid Attribute Combination",
+			"Exactly one of input and input_base64 must be specified.",
+		)
+		return diags
+	case !inputBase64.IsNull() && inputType.ValueString() == "json":
+		diags.AddAttributeError(
+			path.Root("input_base64"),
+			"Invalid Attribute Combination",
+			"input_base64 can only be encrypted with input_type \"binary\".",
+		)
+		return diags
+	}
+
+	if outputType.IsNull() || outputType.IsUnknown() {
+		return diags
+	}
+
+	if inputType.ValueString() == "binary" || !inputBase64.IsNull() {
+		if err := checkOutputShape(outputType.ValueString(), map[string]interface{}{"data": ""}); err != nil {
+			diags.AddAttributeError(
+				path.Root("output_type"),
+				"Invalid Input Shape",
+				fmt.Sprintf("Binary input is stored as a single \"data\" string: %s.", err),
+			)
+		}
+		return diags
+	}
+
+	if input.IsUnknown() || containsUnknownValues(input) {
+		return diags
+	}
+
+	inputValue, err := convertDynamicValueToGo(input)
+	if err != nil {
+		return diags
+	}
+	// dynamicObjectValidator reports inputs that are not objects.
+	inputMap, ok := inputValue.(map[string]interface{})
+	if !ok {
+		return diags
+	}
+
+	if err := checkOutputShape(outputType.ValueString(), inputMap); err != nil {
+		diags.AddAttributeError(
+			path.Root("input"),
+			"Invalid Input Shape",
+			fmt.Sprintf("The input cannot be encrypted with output_type %q: %s.", outputType.ValueString(), err),
+		)
+	}
+	return diags
+}
+
+// base64Validator accepts standard base64 with padding.
+type base64Validator struct{}
+
+func (v base64Validator) Description(ctx context.Context) string {
+	return "value must be base64-encoded"
+}
+
+func (v base64Validator) MarkdownDescription(ctx context.Context) string {
+	return "value must be base64-encoded"
+}
+
+func (v base64Validator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Base64",
+			fmt.Sprintf("Value must be base64-encoded, as produced by filebase64() or base64encode(): %s", err),
 		)
 	}
 }
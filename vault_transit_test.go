@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const testVaultToken = "sops-test-root-token"
+
+// testVaultDevServer starts a throwaway Vault dev server with a Transit key
+// named "sops" and returns its address.
+func testVaultDevServer(t *testing.T) string {
+	t.Helper()
+
+	vault, err := exec.LookPath("vault")
+	if err != nil {
+		t.Skip("vault is not installed")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listenAddress := listener.Addr().String()
+	_ = listener.Close()
+
+	server := exec.Command(vault, "server", "-dev",
+		"-dev-root-token-id="+testVaultToken,
+		"-dev-listen-address="+listenAddress,
+	)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start vault dev server: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Process.Kill()
+		_ = server.Wait()
+	})
+
+	address := "http://" + listenAddress
+	vaultCmd := func(args ...string) ([]byte, error) {
+		cmd := exec.Command(vault, args...)
+		cmd.Env = append(os.Environ(), "VAULT_ADDR="+address, "VAULT_TOKEN="+testVaultToken)
+		return cmd.CombinedOutput()
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := vaultCmd("status"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("vault dev server did not become ready")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if out, err := vaultCmd("secrets", "enable", "transit"); err != nil {
+		t.Fatalf("failed to enable transit engine: %s: %s", err, out)
+	}
+	if out, err := vaultCmd("write", "-f", "transit/keys/sops"); err != nil {
+		t.Fatalf("failed to create transit key: %s: %s", err, out)
+	}
+
+	return address
+}
+
+func TestAccEncryptDecrypt_VaultTransit(t *testing.T) {
+	address := testVaultDevServer(t)
+
+	// The provider configuration must win over whatever the runner has set.
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:1")
+	t.Setenv("VAULT_TOKEN", "ambient-token")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sops" {
+  vault_address = %[1]q
+  vault_token   = %[2]q
+}
+
+resource "sops_encrypt" "test" {
+  input = {
+    secret = "vault-value"
+  }
+  hc_vault_transit_uris = ["%[1]s/v1/transit/keys/sops"]
+}
+
+data "sops_decrypt" "test" {
+  input      = sops_encrypt.test.output
+  input_type = "json"
+}
+`, address, testVaultToken),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_decrypt.test", "output.secret", "vault-value"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEncryptDataSource_InvalidVaultTransitURI(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "sops_encrypt" "test" {
+  input = {
+    secret = "value"
+  }
+  hc_vault_transit_uris = ["https://vault.example.com:8200/transit/sops"]
+}
+`,
+				ExpectError: regexp.MustCompile("must be a Vault Transit key URI"),
+			},
+		},
+	})
+}